@@ -0,0 +1,405 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/types"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// validate checks the declared services for the problems the reflection based ServiceProvider
+// would report at runtime: dependencies that aren't registered, cycles, and Singletons that
+// capture Scoped services. Every problem that is found is included in the returned error.
+func validate(decls *declarations) error {
+	byType := make(map[string]*service, len(decls.services))
+	names := make(map[string]*service, len(decls.services))
+	var errs []error
+	for _, svc := range decls.services {
+		if existing, ok := byType[svc.typ]; ok {
+			errs = append(errs, fmt.Errorf("%s: %s is already registered at %s", svc.pos, svc.typ, existing.pos))
+			continue
+		}
+		byType[svc.typ] = svc
+		name, err := accessorName(svc.typeExpr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", svc.pos, err))
+			continue
+		}
+		if name == "Close" || name == "NewScope" {
+			errs = append(errs, fmt.Errorf("%s: %s would be resolved by a method named %s which is reserved",
+				svc.pos, svc.typ, name))
+			continue
+		}
+		if existing, ok := names[name]; ok {
+			errs = append(errs, fmt.Errorf("%s: %s and %s would both be resolved by a method named %s",
+				svc.pos, existing.typ, svc.typ, name))
+			continue
+		}
+		names[name] = svc
+	}
+	for _, svc := range decls.services {
+		for _, param := range svc.params {
+			if _, ok := byType[param]; !ok {
+				errs = append(errs, fmt.Errorf("%s: missing dependency: %s (%s) depends on %s which is not registered",
+					svc.pos, svc.typ, svc.constructor, param))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	// Find cycles with a depth first search, reporting each cycle from the first service in it
+	// that's visited.
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	states := make(map[string]int, len(decls.services))
+	var path []string
+	var visit func(svc *service)
+	visit = func(svc *service) {
+		switch states[svc.typ] {
+		case visiting:
+			start := slices.Index(path, svc.typ)
+			errs = append(errs, fmt.Errorf("%s: circular dependency: %s",
+				svc.pos, strings.Join(append(slices.Clone(path[start:]), svc.typ), " -> ")))
+			return
+		case visited:
+			return
+		}
+		states[svc.typ] = visiting
+		path = append(path, svc.typ)
+		for _, param := range svc.params {
+			visit(byType[param])
+		}
+		path = path[:len(path)-1]
+		states[svc.typ] = visited
+	}
+	for _, svc := range decls.services {
+		visit(svc)
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	// Singletons are created once for the whole application so any Scoped service they depend on,
+	// directly or through Transient services, would be captured from whichever scope resolved the
+	// Singleton first.
+	var findCaptive func(chain []string, svc *service) ([]string, bool)
+	findCaptive = func(chain []string, svc *service) ([]string, bool) {
+		for _, param := range svc.params {
+			dependency := byType[param]
+			chain := append(slices.Clip(chain), param)
+			switch dependency.lifetime {
+			case "Scoped":
+				return chain, true
+			case "Transient":
+				if chain, ok := findCaptive(chain, dependency); ok {
+					return chain, true
+				}
+			}
+		}
+		return nil, false
+	}
+	for _, svc := range decls.services {
+		if svc.lifetime != "Singleton" {
+			continue
+		}
+		if chain, ok := findCaptive([]string{svc.typ}, svc); ok {
+			errs = append(errs, fmt.Errorf("%s: captive dependency: Singleton %s depends on Scoped %s: %s",
+				svc.pos, svc.typ, chain[len(chain)-1], strings.Join(chain, " -> ")))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// generate renders the Go source of the typed Provider and Scope for the validated declarations.
+func generate(decls *declarations) ([]byte, error) {
+	data := templateData{
+		Source:     decls.file,
+		Package:    decls.pkg,
+		InjectName: decls.injectName,
+	}
+	// Only the packages that appear in the service types need to be imported.
+	used := map[string]bool{decls.injectName: true}
+	byType := make(map[string]templateService, len(decls.services))
+	for _, svc := range decls.services {
+		ast.Inspect(svc.typeExpr, func(node ast.Node) bool {
+			if selector, ok := node.(*ast.SelectorExpr); ok {
+				if ident, ok := selector.X.(*ast.Ident); ok {
+					used[ident.Name] = true
+				}
+			}
+			return true
+		})
+		name, err := accessorName(svc.typeExpr)
+		if err != nil {
+			return nil, err
+		}
+		byType[svc.typ] = templateService{
+			Name:        name,
+			Field:       fieldName(name),
+			Type:        svc.typ,
+			Lifetime:    svc.lifetime,
+			Constructor: svc.constructor,
+			ReturnsErr:  svc.returnsErr,
+		}
+	}
+	for _, svc := range decls.services {
+		tmplSvc := byType[svc.typ]
+		for _, param := range svc.params {
+			tmplSvc.Dependencies = append(tmplSvc.Dependencies, byType[param].Name)
+		}
+		data.UsesFmt = data.UsesFmt || tmplSvc.ReturnsErr || len(tmplSvc.Dependencies) > 0
+		data.Services = append(data.Services, tmplSvc)
+	}
+	data.StdImports = []string{`"context"`, `"errors"`, `"io"`, `"sync"`}
+	if data.UsesFmt {
+		data.StdImports = append(data.StdImports, `"fmt"`)
+	}
+	for name, path := range decls.imports {
+		if !used[name] {
+			continue
+		}
+		spec := strconv.Quote(path)
+		if name != pathBase(path) {
+			spec = name + " " + spec
+		}
+		// Like goimports, the standard library is grouped before other packages.
+		if first, _, _ := strings.Cut(path, "/"); strings.Contains(first, ".") {
+			data.Imports = append(data.Imports, spec)
+		} else {
+			data.StdImports = append(data.StdImports, spec)
+		}
+	}
+	slices.Sort(data.StdImports)
+	slices.Sort(data.Imports)
+
+	var buf bytes.Buffer
+	if err := providerTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	source, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w", err)
+	}
+	return source, nil
+}
+
+type templateData struct {
+	Source     string
+	Package    string
+	InjectName string
+	StdImports []string
+	Imports    []string
+	Services   []templateService
+	// UsesFmt is whether any service has errors to wrap.
+	UsesFmt bool
+}
+
+type templateService struct {
+	// Name is the name of the method that resolves the service.
+	Name string
+	// Field is the prefix of the fields that cache Singleton and Scoped instances.
+	Field        string
+	Type         string
+	Lifetime     string
+	Constructor  string
+	ReturnsErr   bool
+	Dependencies []string
+}
+
+// accessorName names the method which resolves a service after the type name in its type, e.g.
+// DB for *sql.DB.
+func accessorName(typ ast.Expr) (string, error) {
+	switch expr := typ.(type) {
+	case *ast.Ident:
+		return exported(expr.Name), nil
+	case *ast.SelectorExpr:
+		return exported(expr.Sel.Name), nil
+	case *ast.StarExpr:
+		return accessorName(expr.X)
+	case *ast.IndexExpr:
+		return accessorName(expr.X)
+	case *ast.IndexListExpr:
+		return accessorName(expr.X)
+	}
+	return "", fmt.Errorf("cannot name a method after %s: register a named type instead", types.ExprString(typ))
+}
+
+// fieldName unexports an accessor name, lowering a leading initialism as a whole, e.g. db for DB
+// and httpClient for HTTPClient.
+func fieldName(name string) string {
+	runes := []rune(name)
+	for i := range runes {
+		if !unicode.IsUpper(runes[i]) {
+			break
+		}
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
+
+func exported(name string) string {
+	runes := []rune(name)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+func pathBase(path string) string {
+	return path[strings.LastIndex(path, "/")+1:]
+}
+
+var providerTemplate = template.Must(template.New("provider").Parse(`// Code generated by inject-gen from {{.Source}}. DO NOT EDIT.
+
+package {{.Package}}
+
+import ({{range .StdImports}}
+	{{.}}{{end}}
+{{range .Imports}}
+	{{.}}{{end}}
+)
+
+// A Provider creates the services registered in {{.Source}} with plain Go code instead of
+// reflection. Singletons are resolved from the Provider and every service can be resolved from a
+// Scope created with NewScope.
+type Provider struct {
+	root *Scope
+}
+
+// NewProvider creates a Provider.
+func NewProvider() *Provider {
+	root := &Scope{}
+	root.root = root
+	return &Provider{root: root}
+}
+
+// NewScope creates a Scope which shares the Singletons of the Provider and creates its own Scoped
+// services.
+func (p *Provider) NewScope() *Scope {
+	return &Scope{root: p.root}
+}
+
+// Close releases the Singletons created by the Provider, and the Transients they depend on, in the
+// reverse of the order they were created.
+func (p *Provider) Close(ctx context.Context) error {
+	return p.root.Close(ctx)
+}
+{{range .Services}}{{if eq .Lifetime "Singleton"}}
+// {{.Name}} resolves the Singleton {{.Type}}.
+func (p *Provider) {{.Name}}() ({{.Type}}, error) {
+	return p.root.{{.Name}}()
+}
+{{end}}{{end}}
+// A Scope creates the Scoped services registered in {{.Source}} once per Scope and releases them,
+// along with the Transients it creates, when it's closed.
+type Scope struct {
+	root        *Scope
+	mu          sync.Mutex
+	closed      bool
+	disposables []any
+{{range .Services}}{{if ne .Lifetime "Transient"}}
+	{{.Field}}Mu    sync.Mutex
+	{{.Field}}Value {{.Type}}
+	{{.Field}}Done  bool
+{{end}}{{end}}}
+
+// Close releases the instances created by the Scope in the reverse of the order they were created.
+func (s *Scope) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	disposables := s.disposables
+	s.disposables = nil
+	s.mu.Unlock()
+
+	var errs []error
+	for i := len(disposables) - 1; i >= 0; i-- {
+		errs = append(errs, s.dispose(ctx, disposables[i]))
+	}
+	return errors.Join(errs...)
+}
+{{range .Services}}{{$recv := "s"}}{{if eq .Lifetime "Singleton"}}{{$recv = "r"}}{{end}}
+// {{.Name}} resolves the {{.Lifetime}} {{.Type}}.
+func (s *Scope) {{.Name}}() ({{.Type}}, error) {
+{{- if eq .Lifetime "Singleton"}}
+	r := s.root{{end}}
+{{- if ne .Lifetime "Transient"}}
+	{{$recv}}.{{.Field}}Mu.Lock()
+	defer {{$recv}}.{{.Field}}Mu.Unlock()
+	if {{$recv}}.{{.Field}}Done {
+		return {{$recv}}.{{.Field}}Value, nil
+	}{{end}}
+	if err := {{$recv}}.checkOpen(); err != nil {
+		return *new({{.Type}}), err
+	}
+{{- $svc := .}}{{range $i, $dep := .Dependencies}}
+	arg{{$i}}, err := {{$recv}}.{{$dep}}()
+	if err != nil {
+		return *new({{$svc.Type}}), fmt.Errorf("{{$svc.Type}}: %w", err)
+	}{{end}}
+	{{if .ReturnsErr}}service, err{{else}}service{{end}} := {{.Constructor}}({{range $i, $dep := .Dependencies}}{{if $i}}, {{end}}arg{{$i}}{{end}})
+{{- if .ReturnsErr}}
+	if err != nil {
+		return *new({{.Type}}), fmt.Errorf("{{.Type}}: %w", err)
+	}{{end}}
+	if err := {{$recv}}.track(service); err != nil {
+		return *new({{.Type}}), err
+	}
+{{- if ne .Lifetime "Transient"}}
+	{{$recv}}.{{.Field}}Value, {{$recv}}.{{.Field}}Done = service, true{{end}}
+	return service, nil
+}
+{{end}}
+func (s *Scope) checkOpen() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return {{.InjectName}}.ErrProviderClosed
+	}
+	return nil
+}
+
+// track remembers the given service for disposal if it needs it. If the Scope was closed while the
+// service was being created then it is disposed immediately.
+func (s *Scope) track(service any) error {
+	switch service.(type) {
+	case {{.InjectName}}.Disposer, io.Closer:
+	default:
+		return nil
+	}
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return errors.Join({{.InjectName}}.ErrProviderClosed, s.dispose(context.Background(), service))
+	}
+	s.disposables = append(s.disposables, service)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *Scope) dispose(ctx context.Context, service any) error {
+	switch d := service.(type) {
+	case {{.InjectName}}.Disposer:
+		return d.Dispose(ctx)
+	case io.Closer:
+		return d.Close()
+	}
+	return nil
+}
+`))
@@ -0,0 +1,95 @@
+// Command inject-gen generates plain Go code that wires the services of a package together so that
+// missing and circular dependencies, and Singletons that capture Scoped services, are reported when
+// the code is generated rather than when services are resolved.
+//
+// The registrations are read from a declarations file: a Go file whose funcs register services with
+// [inject.RegisterConstructor], naming constructors declared in the same package.
+//
+//	func registerServices(services *inject.ServiceCollection) {
+//		inject.RegisterConstructor[*sql.DB](services, inject.Singleton, OpenDB)
+//		inject.RegisterConstructor[UserRepo](services, inject.Scoped, NewSQLUserRepo)
+//		inject.RegisterConstructor[*UserHandler](services, inject.Transient, NewUserHandler)
+//	}
+//
+// The generated file declares a Provider, created with NewProvider, with a method resolving each
+// Singleton, and a Scope, created with Provider.NewScope, with a method resolving every service.
+// The methods are named after the service types, e.g. DB, UserRepo, and UserHandler. Both have a
+// Close method that releases the instances implementing [inject.Disposer] or [io.Closer] like an
+// [inject.ServiceProvider] does. Parameters of constructors are matched to services by how their
+// types are written, so a parameter of type *sql.DB is satisfied by the service registered as
+// *sql.DB.
+//
+// Constructors may only take registered services. Parameters that an [inject.ServiceProvider]
+// provides without a registration, i.e. [context.Context] and the [inject.Lazy], [inject.Factory],
+// [inject.Optional], and [inject.Options] types, are unsupported by inject-gen and reported as
+// errors, so declarations files using them can only be used with the ServiceProvider.
+//
+// Usage:
+//
+//	inject-gen [-out file] declarations.go
+//
+// It's usually run with go generate:
+//
+//	//go:generate go run github.com/ttd2089/stahp/cmd/inject-gen services.go
+//
+// [inject.RegisterConstructor]: https://pkg.go.dev/github.com/ttd2089/stahp/inject#RegisterConstructor
+// [inject.Disposer]: https://pkg.go.dev/github.com/ttd2089/stahp/inject#Disposer
+// [inject.ServiceProvider]: https://pkg.go.dev/github.com/ttd2089/stahp/inject#ServiceProvider
+// [inject.Lazy]: https://pkg.go.dev/github.com/ttd2089/stahp/inject#Lazy
+// [inject.Factory]: https://pkg.go.dev/github.com/ttd2089/stahp/inject#Factory
+// [inject.Optional]: https://pkg.go.dev/github.com/ttd2089/stahp/inject#Optional
+// [inject.Options]: https://pkg.go.dev/github.com/ttd2089/stahp/inject#Options
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stderr); err != nil {
+		fmt.Fprintf(os.Stderr, "inject-gen: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stderr io.Writer) error {
+	flags := flag.NewFlagSet("inject-gen", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	out := flags.String("out", "", "the file to write, defaults to the declarations file with a _gen.go suffix")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: inject-gen [-out file] declarations.go")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("expected 1 declarations file; got %d", flags.NArg())
+	}
+	in := flags.Arg(0)
+	if *out == "" {
+		*out = strings.TrimSuffix(in, ".go") + "_gen.go"
+	}
+	if filepath.Clean(*out) == filepath.Clean(in) {
+		return fmt.Errorf("cannot overwrite the declarations file %s", in)
+	}
+
+	decls, err := parseDeclarations(in)
+	if err != nil {
+		return err
+	}
+	if err := validate(decls); err != nil {
+		return err
+	}
+	source, err := generate(decls)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(*out, source, 0o644)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+
+	t.Run("generated code matches testdata/app", func(t *testing.T) {
+		out := filepath.Join(t.TempDir(), "services_gen.go")
+		if err := run([]string{"-out", out, "testdata/app/services.go"}, &bytes.Buffer{}); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		expected, _ := os.ReadFile("testdata/app/services_gen.go")
+		actual, _ := os.ReadFile(out)
+		if !bytes.Equal(actual, expected) {
+			t.Fatalf("generated code differs from testdata/app/services_gen.go; regenerate it with\n\tgo run . testdata/app/services.go\ngot:\n%s", actual)
+		}
+	})
+
+	t.Run("generated code passes the tests in testdata/app", func(t *testing.T) {
+		if testing.Short() {
+			t.Skip("skipping go test of generated code in short mode")
+		}
+		output, err := exec.Command("go", "test", "./testdata/app").CombinedOutput()
+		if err != nil {
+			t.Fatalf("unexpected error: %q\n%s", err, output)
+		}
+	})
+
+	tests := []struct {
+		name         string
+		declarations string
+		expected     []string
+	}{
+		{
+			name: "reports missing dependencies",
+			declarations: `
+				inject.RegisterConstructor[*Handler](services, inject.Transient, NewHandler)`,
+			expected: []string{"missing dependency: *Handler (NewHandler) depends on *Repo which is not registered"},
+		},
+		{
+			name: "reports circular dependencies",
+			declarations: `
+				inject.RegisterConstructor[*Handler](services, inject.Transient, NewHandler)
+				inject.RegisterConstructor[*Repo](services, inject.Transient, NewCyclicRepo)`,
+			expected: []string{"circular dependency: *Handler -> *Repo -> *Handler"},
+		},
+		{
+			name: "reports captive dependencies",
+			declarations: `
+				inject.RegisterConstructor[*Handler](services, inject.Singleton, NewHandler)
+				inject.RegisterConstructor[*Repo](services, inject.Scoped, NewRepo)`,
+			expected: []string{"captive dependency: Singleton *Handler depends on Scoped *Repo: *Handler -> *Repo"},
+		},
+		{
+			name: "reports constructors that are not declared",
+			declarations: `
+				inject.RegisterConstructor[*Repo](services, inject.Scoped, NewMissingRepo)`,
+			expected: []string{"constructor NewMissingRepo is not declared in package app"},
+		},
+		{
+			name: "reports services registered more than once",
+			declarations: `
+				inject.RegisterConstructor[*Repo](services, inject.Scoped, NewRepo)
+				inject.RegisterConstructor[*Repo](services, inject.Singleton, NewRepo)`,
+			expected: []string{"*Repo is already registered"},
+		},
+		{
+			name: "reports context parameters as unsupported",
+			declarations: `
+				inject.RegisterConstructor[*Repo](services, inject.Scoped, NewContextRepo)`,
+			expected: []string{"constructor NewContextRepo has a parameter of type context.Context which is unsupported by inject-gen"},
+		},
+		{
+			name: "reports Lazy, Factory, Optional, and Options parameters as unsupported",
+			declarations: `
+				inject.RegisterConstructor[*Repo](services, inject.Scoped, NewRepo)
+				inject.RegisterConstructor[*Handler](services, inject.Transient, NewLazyHandler)`,
+			expected: []string{"constructor NewLazyHandler has a parameter of type di.Lazy[*Repo] which is unsupported by inject-gen"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, filepath.Join(dir, "app.go"), `package app
+
+				import (
+					"context"
+
+					di "github.com/ttd2089/stahp/inject"
+				)
+
+				type Repo struct{}
+
+				func NewRepo() *Repo { return &Repo{} }
+
+				func NewContextRepo(context.Context) *Repo { return &Repo{} }
+
+				func NewCyclicRepo(*Handler) *Repo { return &Repo{} }
+
+				type Handler struct{}
+
+				func NewHandler(*Repo) *Handler { return &Handler{} }
+
+				func NewLazyHandler(di.Lazy[*Repo]) *Handler { return &Handler{} }
+			`)
+			writeFile(t, filepath.Join(dir, "services.go"), `package app
+
+				import "github.com/ttd2089/stahp/inject"
+
+				func registerServices(services *inject.ServiceCollection) {`+test.declarations+`
+				}
+			`)
+
+			err := run([]string{filepath.Join(dir, "services.go")}, &bytes.Buffer{})
+
+			if err == nil {
+				t.Fatalf("expected error; got nil")
+			}
+			for _, expected := range test.expected {
+				if !strings.Contains(err.Error(), expected) {
+					t.Fatalf("expected error containing %q; got %q", expected, err)
+				}
+			}
+			if _, err := os.Stat(filepath.Join(dir, "services_gen.go")); !os.IsNotExist(err) {
+				t.Fatalf("expected no generated file; got %v", err)
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, name string, content string) {
+	t.Helper()
+	if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const injectPath = "github.com/ttd2089/stahp/inject"
+
+// A declarations file is a Go file whose funcs register services with RegisterConstructor calls.
+type declarations struct {
+	file    string
+	pkg     string
+	imports map[string]string
+	// injectName is the name the inject package is imported as.
+	injectName string
+	services   []*service
+}
+
+// A service is a registration read from a RegisterConstructor call in a declarations file.
+type service struct {
+	pos token.Position
+	// typ is the service type as written in the type argument, e.g. *sql.DB.
+	typ      string
+	typeExpr ast.Expr
+	lifetime string
+	// constructor is the name of the constructor func, which must be declared in the package of
+	// the declarations file.
+	constructor string
+	params      []string
+	returnsErr  bool
+}
+
+// parseDeclarations reads the registrations in the given declarations file and the signatures
+// of their constructors from the other files in the same directory.
+func parseDeclarations(file string) (*declarations, error) {
+	fset := token.NewFileSet()
+	parsed, err := parser.ParseFile(fset, file, nil, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	decls := &declarations{
+		file:    filepath.Base(file),
+		pkg:     parsed.Name.Name,
+		imports: importNames(parsed),
+	}
+	for name, path := range decls.imports {
+		if path == injectPath {
+			decls.injectName = name
+		}
+	}
+	if decls.injectName == "" {
+		return nil, fmt.Errorf("%s does not import %s", file, injectPath)
+	}
+
+	var errs []error
+	ast.Inspect(parsed, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		index, ok := call.Fun.(*ast.IndexExpr)
+		if !ok || !isSelector(index.X, decls.injectName, "RegisterConstructor") {
+			return true
+		}
+		svc, err := decls.parseRegistration(fset, call, index.Index)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", fset.Position(call.Pos()), err))
+			return false
+		}
+		decls.services = append(decls.services, svc)
+		return false
+	})
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	if len(decls.services) == 0 {
+		return nil, fmt.Errorf("%s has no %s.RegisterConstructor calls", file, decls.injectName)
+	}
+
+	constructors, err := parseConstructors(fset, filepath.Dir(file), decls.pkg)
+	if err != nil {
+		return nil, err
+	}
+	for _, svc := range decls.services {
+		fn, ok := constructors[svc.constructor]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: constructor %s is not declared in package %s", svc.pos, svc.constructor, decls.pkg))
+			continue
+		}
+		if err := svc.readSignature(fn.decl.Type, fn.imports); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", svc.pos, err))
+		}
+	}
+	return decls, errors.Join(errs...)
+}
+
+// parseRegistration reads a RegisterConstructor call, e.g.
+//
+//	inject.RegisterConstructor[UserRepo](services, inject.Scoped, NewSQLUserRepo)
+func (decls *declarations) parseRegistration(fset *token.FileSet, call *ast.CallExpr, typeArg ast.Expr) (*service, error) {
+	if len(call.Args) != 3 {
+		return nil, fmt.Errorf("expected 3 arguments to RegisterConstructor; got %d", len(call.Args))
+	}
+	svc := &service{
+		pos:      fset.Position(call.Pos()),
+		typ:      types.ExprString(typeArg),
+		typeExpr: typeArg,
+	}
+	lifetime, ok := call.Args[1].(*ast.SelectorExpr)
+	if !ok || !isSelector(lifetime, decls.injectName, lifetime.Sel.Name) {
+		return nil, fmt.Errorf("lifetime must be %[1]s.Singleton, %[1]s.Scoped, or %[1]s.Transient; got %s",
+			decls.injectName, types.ExprString(call.Args[1]))
+	}
+	switch svc.lifetime = lifetime.Sel.Name; svc.lifetime {
+	case "Singleton", "Scoped", "Transient":
+	default:
+		return nil, fmt.Errorf("unknown lifetime %s", types.ExprString(lifetime))
+	}
+	constructor, ok := call.Args[2].(*ast.Ident)
+	if !ok {
+		return nil, fmt.Errorf("constructor must be the name of a func declared in the package; got %s",
+			types.ExprString(call.Args[2]))
+	}
+	svc.constructor = constructor.Name
+	return svc, nil
+}
+
+// readSignature reads the parameter types and the results of the service's constructor. The
+// imports are those of the file declaring the constructor, by the names they're imported as.
+func (svc *service) readSignature(fn *ast.FuncType, imports map[string]string) error {
+	if fn.TypeParams != nil {
+		return fmt.Errorf("constructor %s must not be generic", svc.constructor)
+	}
+	for _, param := range fn.Params.List {
+		if _, ok := param.Type.(*ast.Ellipsis); ok {
+			return fmt.Errorf("constructor %s must not be variadic", svc.constructor)
+		}
+		typ := types.ExprString(param.Type)
+		if unsupportedParam(param.Type, imports) {
+			return fmt.Errorf("constructor %s has a parameter of type %s which is unsupported by inject-gen",
+				svc.constructor, typ)
+		}
+		for range max(len(param.Names), 1) {
+			svc.params = append(svc.params, typ)
+		}
+	}
+	var results []string
+	if fn.Results != nil {
+		for _, result := range fn.Results.List {
+			for range max(len(result.Names), 1) {
+				results = append(results, types.ExprString(result.Type))
+			}
+		}
+	}
+	switch {
+	case len(results) == 1:
+	case len(results) == 2 && results[1] == "error":
+		svc.returnsErr = true
+	default:
+		return fmt.Errorf("constructor %s must return a service and optionally an error", svc.constructor)
+	}
+	return nil
+}
+
+// unsupportedParam reports whether the given parameter type is one the reflection based
+// ServiceProvider provides without a registration, i.e. context.Context and the Lazy, Factory,
+// Optional, and Options types of the inject package, which generated code can't provide.
+func unsupportedParam(typ ast.Expr, imports map[string]string) bool {
+	switch expr := typ.(type) {
+	case *ast.IndexExpr:
+		typ = expr.X
+	case *ast.IndexListExpr:
+		typ = expr.X
+	}
+	selector, ok := typ.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	ident, ok := selector.X.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	switch imports[ident.Name] {
+	case "context":
+		return selector.Sel.Name == "Context"
+	case injectPath:
+		switch selector.Sel.Name {
+		case "Lazy", "Factory", "Optional", "Options":
+			return true
+		}
+	}
+	return false
+}
+
+// A constructor is a func declared in the package of a declarations file.
+type constructor struct {
+	decl *ast.FuncDecl
+	// imports are the imports of the file declaring the func by the names they're imported as.
+	imports map[string]string
+}
+
+// parseConstructors finds the funcs declared in the non-test Go files of the package in dir.
+func parseConstructors(fset *token.FileSet, dir string, pkg string) (map[string]constructor, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	funcs := make(map[string]constructor)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		parsed, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		if parsed.Name.Name != pkg {
+			continue
+		}
+		imports := importNames(parsed)
+		for _, decl := range parsed.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil {
+				funcs[fn.Name.Name] = constructor{fn, imports}
+			}
+		}
+	}
+	return funcs, nil
+}
+
+// importNames maps the names the packages imported by the given file are imported as to their
+// paths.
+func importNames(file *ast.File) map[string]string {
+	imports := make(map[string]string, len(file.Imports))
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		name := filepath.Base(path)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = path
+	}
+	return imports
+}
+
+func isSelector(expr ast.Expr, pkg string, name string) bool {
+	selector, ok := expr.(*ast.SelectorExpr)
+	if !ok || selector.Sel.Name != name {
+		return false
+	}
+	ident, ok := selector.X.(*ast.Ident)
+	return ok && ident.Name == pkg
+}
@@ -0,0 +1,57 @@
+// Package app is an application wired together by inject-gen.
+package app
+
+import (
+	"context"
+	"log/slog"
+	"os"
+)
+
+func NewLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stderr, nil))
+}
+
+type DB struct {
+	Logger *slog.Logger
+	Closed bool
+}
+
+func OpenDB(logger *slog.Logger) (*DB, error) {
+	return &DB{Logger: logger}, nil
+}
+
+func (db *DB) Close() error {
+	db.Closed = true
+	return nil
+}
+
+type UserRepo interface {
+	User(id string) string
+}
+
+type sqlUserRepo struct {
+	db       *DB
+	Disposed bool
+}
+
+func NewSQLUserRepo(db *DB) *sqlUserRepo {
+	return &sqlUserRepo{db: db}
+}
+
+func (repo *sqlUserRepo) User(id string) string {
+	return "user " + id
+}
+
+func (repo *sqlUserRepo) Dispose(context.Context) error {
+	repo.Disposed = true
+	return nil
+}
+
+type UserHandler struct {
+	Repo   UserRepo
+	Logger *slog.Logger
+}
+
+func NewUserHandler(repo UserRepo, logger *slog.Logger) *UserHandler {
+	return &UserHandler{Repo: repo, Logger: logger}
+}
@@ -0,0 +1,54 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	di "github.com/ttd2089/stahp/inject"
+)
+
+func TestProvider(t *testing.T) {
+
+	t.Run("shares Singletons and creates Scoped services per Scope", func(t *testing.T) {
+		provider := NewProvider()
+		first, second := provider.NewScope(), provider.NewScope()
+
+		firstHandler, err := first.UserHandler()
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		secondHandler, _ := second.UserHandler()
+		again, _ := first.UserHandler()
+		db, _ := provider.DB()
+
+		if firstHandler == again {
+			t.Fatalf("expected distinct Transient instances")
+		}
+		if firstHandler.Repo != again.Repo || firstHandler.Repo == secondHandler.Repo {
+			t.Fatalf("expected one Scoped instance per Scope")
+		}
+		if firstHandler.Repo.(*sqlUserRepo).db != db || db.Logger != firstHandler.Logger {
+			t.Fatalf("expected Singletons to be shared")
+		}
+	})
+
+	t.Run("disposes instances when closed", func(t *testing.T) {
+		provider := NewProvider()
+		scope := provider.NewScope()
+		repo, _ := scope.UserRepo()
+		db, _ := provider.DB()
+
+		_ = scope.Close(context.Background())
+		if !repo.(*sqlUserRepo).Disposed || db.Closed {
+			t.Fatalf("expected only the Scoped instance to be disposed")
+		}
+		_ = provider.Close(context.Background())
+		if !db.Closed {
+			t.Fatalf("expected the Singleton to be disposed")
+		}
+		if _, err := scope.UserHandler(); !errors.Is(err, di.ErrProviderClosed) {
+			t.Fatalf("expected %q; got %q", di.ErrProviderClosed, err)
+		}
+	})
+}
@@ -0,0 +1,16 @@
+package app
+
+import (
+	"log/slog"
+
+	di "github.com/ttd2089/stahp/inject"
+)
+
+//go:generate go run github.com/ttd2089/stahp/cmd/inject-gen services.go
+
+func registerServices(services *di.ServiceCollection) {
+	di.RegisterConstructor[*slog.Logger](services, di.Singleton, NewLogger)
+	di.RegisterConstructor[*DB](services, di.Singleton, OpenDB)
+	di.RegisterConstructor[UserRepo](services, di.Scoped, NewSQLUserRepo)
+	di.RegisterConstructor[*UserHandler](services, di.Transient, NewUserHandler)
+}
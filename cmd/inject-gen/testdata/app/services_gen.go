@@ -0,0 +1,214 @@
+// Code generated by inject-gen from services.go. DO NOT EDIT.
+
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+
+	di "github.com/ttd2089/stahp/inject"
+)
+
+// A Provider creates the services registered in services.go with plain Go code instead of
+// reflection. Singletons are resolved from the Provider and every service can be resolved from a
+// Scope created with NewScope.
+type Provider struct {
+	root *Scope
+}
+
+// NewProvider creates a Provider.
+func NewProvider() *Provider {
+	root := &Scope{}
+	root.root = root
+	return &Provider{root: root}
+}
+
+// NewScope creates a Scope which shares the Singletons of the Provider and creates its own Scoped
+// services.
+func (p *Provider) NewScope() *Scope {
+	return &Scope{root: p.root}
+}
+
+// Close releases the Singletons created by the Provider, and the Transients they depend on, in the
+// reverse of the order they were created.
+func (p *Provider) Close(ctx context.Context) error {
+	return p.root.Close(ctx)
+}
+
+// Logger resolves the Singleton *slog.Logger.
+func (p *Provider) Logger() (*slog.Logger, error) {
+	return p.root.Logger()
+}
+
+// DB resolves the Singleton *DB.
+func (p *Provider) DB() (*DB, error) {
+	return p.root.DB()
+}
+
+// A Scope creates the Scoped services registered in services.go once per Scope and releases them,
+// along with the Transients it creates, when it's closed.
+type Scope struct {
+	root        *Scope
+	mu          sync.Mutex
+	closed      bool
+	disposables []any
+
+	loggerMu    sync.Mutex
+	loggerValue *slog.Logger
+	loggerDone  bool
+
+	dbMu    sync.Mutex
+	dbValue *DB
+	dbDone  bool
+
+	userRepoMu    sync.Mutex
+	userRepoValue UserRepo
+	userRepoDone  bool
+}
+
+// Close releases the instances created by the Scope in the reverse of the order they were created.
+func (s *Scope) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	disposables := s.disposables
+	s.disposables = nil
+	s.mu.Unlock()
+
+	var errs []error
+	for i := len(disposables) - 1; i >= 0; i-- {
+		errs = append(errs, s.dispose(ctx, disposables[i]))
+	}
+	return errors.Join(errs...)
+}
+
+// Logger resolves the Singleton *slog.Logger.
+func (s *Scope) Logger() (*slog.Logger, error) {
+	r := s.root
+	r.loggerMu.Lock()
+	defer r.loggerMu.Unlock()
+	if r.loggerDone {
+		return r.loggerValue, nil
+	}
+	if err := r.checkOpen(); err != nil {
+		return *new(*slog.Logger), err
+	}
+	service := NewLogger()
+	if err := r.track(service); err != nil {
+		return *new(*slog.Logger), err
+	}
+	r.loggerValue, r.loggerDone = service, true
+	return service, nil
+}
+
+// DB resolves the Singleton *DB.
+func (s *Scope) DB() (*DB, error) {
+	r := s.root
+	r.dbMu.Lock()
+	defer r.dbMu.Unlock()
+	if r.dbDone {
+		return r.dbValue, nil
+	}
+	if err := r.checkOpen(); err != nil {
+		return *new(*DB), err
+	}
+	arg0, err := r.Logger()
+	if err != nil {
+		return *new(*DB), fmt.Errorf("*DB: %w", err)
+	}
+	service, err := OpenDB(arg0)
+	if err != nil {
+		return *new(*DB), fmt.Errorf("*DB: %w", err)
+	}
+	if err := r.track(service); err != nil {
+		return *new(*DB), err
+	}
+	r.dbValue, r.dbDone = service, true
+	return service, nil
+}
+
+// UserRepo resolves the Scoped UserRepo.
+func (s *Scope) UserRepo() (UserRepo, error) {
+	s.userRepoMu.Lock()
+	defer s.userRepoMu.Unlock()
+	if s.userRepoDone {
+		return s.userRepoValue, nil
+	}
+	if err := s.checkOpen(); err != nil {
+		return *new(UserRepo), err
+	}
+	arg0, err := s.DB()
+	if err != nil {
+		return *new(UserRepo), fmt.Errorf("UserRepo: %w", err)
+	}
+	service := NewSQLUserRepo(arg0)
+	if err := s.track(service); err != nil {
+		return *new(UserRepo), err
+	}
+	s.userRepoValue, s.userRepoDone = service, true
+	return service, nil
+}
+
+// UserHandler resolves the Transient *UserHandler.
+func (s *Scope) UserHandler() (*UserHandler, error) {
+	if err := s.checkOpen(); err != nil {
+		return *new(*UserHandler), err
+	}
+	arg0, err := s.UserRepo()
+	if err != nil {
+		return *new(*UserHandler), fmt.Errorf("*UserHandler: %w", err)
+	}
+	arg1, err := s.Logger()
+	if err != nil {
+		return *new(*UserHandler), fmt.Errorf("*UserHandler: %w", err)
+	}
+	service := NewUserHandler(arg0, arg1)
+	if err := s.track(service); err != nil {
+		return *new(*UserHandler), err
+	}
+	return service, nil
+}
+
+func (s *Scope) checkOpen() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return di.ErrProviderClosed
+	}
+	return nil
+}
+
+// track remembers the given service for disposal if it needs it. If the Scope was closed while the
+// service was being created then it is disposed immediately.
+func (s *Scope) track(service any) error {
+	switch service.(type) {
+	case di.Disposer, io.Closer:
+	default:
+		return nil
+	}
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return errors.Join(di.ErrProviderClosed, s.dispose(context.Background(), service))
+	}
+	s.disposables = append(s.disposables, service)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *Scope) dispose(ctx context.Context, service any) error {
+	switch d := service.(type) {
+	case di.Disposer:
+		return d.Dispose(ctx)
+	case io.Closer:
+		return d.Close()
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package inject
+
+// A BuildOption configures the [ServiceProvider] created by [ServiceCollection.Build].
+type BuildOption func(*buildOptions)
+
+type buildOptions struct {
+	strictLifetimes bool
+	eagerSingletons bool
+	parallelEager   bool
+	validateOnBuild bool
+	onDispose       []func(any, error)
+	observers       []Observer
+}
+
+// StrictLifetimes makes the [ServiceProvider] refuse to resolve [Scoped] services from the top
+// level ServiceProvider, where they would live as long as the application. This includes services
+// resolved by the factories of [Singleton] services, which is how a Singleton captures a Scoped
+// service whose dependencies can't be seen by [ServiceCollection.Build], e.g. because it was
+// registered with [RegisterFunc]. Without this option only the dependencies known to Build are
+// checked. It also refuses to resolve [Transient] services whose instances must be disposed from
+// the top level ServiceProvider, which would hold them until it's closed, unless they're
+// dependencies of a Singleton. Attempts to resolve services in violation of the lifetimes return
+// [ErrCaptiveDependency].
+func StrictLifetimes() BuildOption {
+	return func(options *buildOptions) {
+		options.strictLifetimes = true
+	}
+}
+
+// EagerSingletons makes [ServiceCollection.Build] create every [Singleton] instance before it
+// returns so that failures happen at startup rather than on the first request that needs them.
+// Singletons are created in dependency order and Build returns every error that occurs.
+func EagerSingletons() BuildOption {
+	return func(options *buildOptions) {
+		options.eagerSingletons = true
+	}
+}
+
+// ParallelEagerSingletons is like [EagerSingletons] but creates Singletons that don't depend on
+// each other concurrently, which can shorten startup when their factories are slow, e.g. because
+// they connect to remote services.
+func ParallelEagerSingletons() BuildOption {
+	return func(options *buildOptions) {
+		options.eagerSingletons = true
+		options.parallelEager = true
+	}
+}
+
+// ValidateOnBuild makes [ServiceCollection.Build] resolve every registration from a throwaway
+// [ServiceProvider] and scope, which are closed before Build returns, and report every error that
+// occurs. Unlike the validation Build always does, this includes the errors returned by factories
+// and the dependencies of services registered with funcs. Note that this runs every factory so it
+// should only be used when the factories are free of side effects that matter.
+func ValidateOnBuild() BuildOption {
+	return func(options *buildOptions) {
+		options.validateOnBuild = true
+	}
+}
+
+// OnDispose makes the [ServiceProvider] call the given func with every instance it disposes, i.e.
+// every instance implementing [Disposer] or [io.Closer] released when a ServiceProvider or one of
+// its scopes is closed, along with the error disposing it returned. It's mostly useful for
+// asserting that instances are released in tests.
+func OnDispose(onDispose func(service any, err error)) BuildOption {
+	return func(options *buildOptions) {
+		options.onDispose = append(options.onDispose, onDispose)
+	}
+}
+
+// Observe makes the [ServiceProvider] notify the given [Observer] before and after every
+// registration it resolves, e.g. to find the factories that make startup slow with a
+// [StatsCollector].
+func Observe(observer Observer) BuildOption {
+	return func(options *buildOptions) {
+		options.observers = append(options.observers, observer)
+	}
+}
@@ -0,0 +1,109 @@
+package inject
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var (
+	errorType   = reflect.TypeFor[error]()
+	contextType = reflect.TypeFor[context.Context]()
+)
+
+// RegisterConstructor registers a constructor function to create the implementations of the
+// service type Service when instances are resolved from a [ServiceProvider] built from the given
+// [ServiceCollection]. The constructor may take any number of parameters, each of which is
+// resolved from the same [ServiceProvider], and must return a value assignable to Service,
+// optionally followed by an error. Unlike factories registered with [RegisterFunc], the
+// dependencies of a constructor are known when the [ServiceProvider] is built, so missing and
+// circular dependencies are reported by [ServiceCollection.Build]. A parameter of type
+// [context.Context] isn't resolved; it receives the context the service is resolved with like the
+// factories registered with [RegisterFuncContext].
+//
+//	inject.RegisterConstructor[UserRepo](&services, inject.Scoped, NewSQLUserRepo)
+func RegisterConstructor[Service any](services *ServiceCollection, lifetime ServiceLifetime, constructor any) error {
+	if services == nil {
+		return errors.New("cannot register types to a nil ServiceProvider")
+	}
+
+	serviceType := reflect.TypeFor[Service]()
+	ctorType := reflect.TypeOf(constructor)
+	if err := validateConstructor(ctorType); err != nil {
+		return err
+	}
+	ctor := reflect.ValueOf(constructor)
+	if ctor.IsNil() {
+		return errors.New("cannot register nil constructor")
+	}
+	implType := ctorType.Out(0)
+
+	if !implType.AssignableTo(serviceType) {
+		return ErrInvalidImplementation
+	}
+
+	if lifetime != Transient && implType.Kind() == reflect.Struct {
+		return ErrNonTransientStruct
+	}
+
+	var dependencies []dependency
+	for i := range ctorType.NumIn() {
+		if ctorType.In(i) != contextType {
+			dependencies = append(dependencies, dependency{serviceKey: keyFor(ctorType.In(i))})
+		}
+	}
+
+	return services.addRegistration(keyFor(serviceType), serviceRegistration{
+		lifetime:     lifetime,
+		implType:     implType,
+		dependencies: dependencies,
+		factory: func(resolver ServiceResolver) (any, error) {
+			args := make([]reflect.Value, ctorType.NumIn())
+			remaining := dependencies
+			for i := range args {
+				if ctorType.In(i) == contextType {
+					args[i] = reflect.ValueOf(contextOf(resolver))
+					continue
+				}
+				dependency := remaining[0]
+				remaining = remaining[1:]
+				arg, err := resolveKey(resolver, dependency.serviceKey)
+				if err != nil {
+					return nil, err
+				}
+				if arg == nil {
+					args[i] = reflect.Zero(dependency.type_)
+					continue
+				}
+				args[i] = reflect.ValueOf(arg)
+				if !args[i].Type().AssignableTo(dependency.type_) {
+					return nil, fmt.Errorf("ServiceResolver returned %T when %v was requested", arg, dependency.type_)
+				}
+			}
+			results := ctor.Call(args)
+			if len(results) == 2 && !results[1].IsNil() {
+				return nil, results[1].Interface().(error)
+			}
+			return results[0].Interface(), nil
+		},
+	}, appendRegistration)
+}
+
+func validateConstructor(ctorType reflect.Type) error {
+	if ctorType == nil || ctorType.Kind() != reflect.Func {
+		return fmt.Errorf("constructor must be a func; got %v", ctorType)
+	}
+	if ctorType.IsVariadic() {
+		return fmt.Errorf("constructor must not be variadic; got %v", ctorType)
+	}
+	switch ctorType.NumOut() {
+	case 1:
+		return nil
+	case 2:
+		if ctorType.Out(1) == errorType {
+			return nil
+		}
+	}
+	return fmt.Errorf("constructor must return a service and optionally an error; got %v", ctorType)
+}
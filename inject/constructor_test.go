@@ -0,0 +1,72 @@
+package inject
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRegisterConstructor(t *testing.T) {
+
+	t.Run("invalid constructors return error", func(t *testing.T) {
+		for name, ctor := range map[string]any{
+			"nil":           nil,
+			"not a func":    1,
+			"no results":    func() {},
+			"variadic":      func(...int) *assignableToFooer { return nil },
+			"non-error":     func() (*assignableToFooer, int) { return nil, 0 },
+			"too many":      func() (*assignableToFooer, int, error) { return nil, 0, nil },
+			"nil func":      (func() *assignableToFooer)(nil),
+			"unassignable":  func() *structWithUnexportedFields { return nil },
+			"scoped struct": func() assignableToFooer { return assignableToFooer{} },
+		} {
+			t.Run(name, func(t *testing.T) {
+				services := ServiceCollection{}
+				if err := RegisterConstructor[fooer](&services, Scoped, ctor); err == nil {
+					t.Fatal("expected error; got <nil>")
+				}
+			})
+		}
+	})
+
+	t.Run("parameters are resolved", func(t *testing.T) {
+		expected := &structWithUnexportedFields{id: 1}
+		services := ServiceCollection{}
+		RegisterFunc[*structWithUnexportedFields](&services, Singleton, func(ServiceResolver) (*structWithUnexportedFields, error) {
+			return expected, nil
+		})
+		var actual *structWithUnexportedFields
+		RegisterConstructor[fooer](&services, Transient, func(s *structWithUnexportedFields) (*assignableToFooer, error) {
+			actual = s
+			return &assignableToFooer{}, nil
+		})
+		provider, _ := services.Build()
+		if _, err := Resolve[fooer](&provider); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if actual != expected {
+			t.Fatalf("expected %p; got %p", expected, actual)
+		}
+	})
+
+	t.Run("constructor errors are returned", func(t *testing.T) {
+		expectedErr := errors.New("expected error")
+		services := ServiceCollection{}
+		RegisterConstructor[fooer](&services, Transient, func() (*assignableToFooer, error) {
+			return nil, expectedErr
+		})
+		provider, _ := services.Build()
+		if _, err := Resolve[fooer](&provider); !errors.Is(err, expectedErr) {
+			t.Fatalf("expected %q; got %q", expectedErr, err)
+		}
+	})
+
+	t.Run("missing parameter returns error from Build", func(t *testing.T) {
+		services := ServiceCollection{}
+		RegisterConstructor[fooer](&services, Transient, func(*structWithUnexportedFields) *assignableToFooer {
+			return &assignableToFooer{}
+		})
+		if _, err := services.Build(); !errors.Is(err, ErrMissingDependency) {
+			t.Fatalf("expected %q; got %q", ErrMissingDependency, err)
+		}
+	})
+}
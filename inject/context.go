@@ -0,0 +1,17 @@
+package inject
+
+import "context"
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx which carries the given [ServiceProvider]. This is typically
+// used to make a request scope available to the code that handles the request.
+func NewContext(ctx context.Context, provider *ServiceProvider) context.Context {
+	return context.WithValue(ctx, contextKey{}, provider)
+}
+
+// FromContext returns the [ServiceProvider] carried by ctx, if any.
+func FromContext(ctx context.Context) (*ServiceProvider, bool) {
+	provider, ok := ctx.Value(contextKey{}).(*ServiceProvider)
+	return provider, ok && provider != nil
+}
@@ -0,0 +1,111 @@
+package inject
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+type decoratorFunc func(any, ServiceResolver) (any, error)
+
+// RegisterDecorator registers a function which wraps the implementations of the service type
+// Service when they are resolved, e.g. to add caching or metrics to a repository. The decorator is
+// given the instance created by the registered implementation, or by the previous decorator when
+// there are several, and returns the instance to use in its place. Decorators are applied in the
+// order they were registered, to every implementation of the service type, regardless of whether
+// the implementations were registered before or after the decorator.
+//
+// Decorated instances have the same lifetime as the implementation they decorate, so a decorated
+// [Singleton] is created and decorated once, and a decorated [Scoped] service is created and
+// decorated once per scope.
+func RegisterDecorator[Service any](
+	services *ServiceCollection,
+	decorator func(inner Service, resolver ServiceResolver) (Service, error),
+) error {
+	if services == nil {
+		return errors.New("cannot register decorators to a nil ServiceProvider")
+	}
+	if decorator == nil {
+		return errors.New("cannot register nil decorator")
+	}
+	serviceType := reflect.TypeFor[Service]()
+	key := keyFor(serviceType)
+	decorate := func(inner any, resolver ServiceResolver) (any, error) {
+		// A nil interface can't be asserted to Service but it's a valid Service, i.e. the zero value.
+		typed, ok := inner.(Service)
+		if !ok && inner != nil {
+			return nil, fmt.Errorf("cannot decorate %T as %v", inner, serviceType)
+		}
+		return decorator(typed, resolver)
+	}
+	return services.update(func(target *ServiceCollection) error {
+		if target.decorators == nil {
+			target.decorators = make(map[serviceKey][]decoratorFunc)
+		}
+		target.decorators[key] = append(target.decorators[key], decorate)
+		return nil
+	})
+}
+
+// decorate returns copies of the given registrations which apply the given decorators to the
+// instances created by the original registrations.
+func decorate(registrations []*serviceRegistration, decorators []decoratorFunc) []*serviceRegistration {
+	if len(decorators) == 0 {
+		return slices.Clone(registrations)
+	}
+	decorators = slices.Clone(decorators)
+	decorated := make([]*serviceRegistration, len(registrations))
+	for i, registration := range registrations {
+		copied := *registration
+		copied.decorated = registration
+		copied.decorators = decorators
+		decorated[i] = &copied
+	}
+	return decorated
+}
+
+// applyDecorators provides the instance of the registration the given registration decorates and
+// applies its decorators to it. The undecorated instance is shared according to its lifetime like
+// any other, so the service types registered together with [RegisterAs] still share it when only
+// some of them are decorated. Every instance the decorators create is tracked in s, not just the
+// outermost, so that a disposable service wrapped by a decorator that isn't disposable is still
+// disposed.
+func (provider *ServiceProvider) applyDecorators(
+	ctx context.Context,
+	s *scope,
+	path []serviceKey,
+	key serviceKey,
+	registration *serviceRegistration,
+) (any, error) {
+	service, _, err := provider.create(ctx, path, key, registration.decorated)
+	if err != nil {
+		return nil, err
+	}
+	created := []any{service}
+	for _, decorator := range registration.decorators {
+		if service, err = decorator(service, resolution{provider, path, ctx}); err != nil {
+			return nil, err
+		}
+		// Decorators often return the instance they're given, which is already tracked.
+		if isDisposable(service) && !slices.ContainsFunc(created, func(instance any) bool { return sameInstance(instance, service) }) {
+			if err := provider.track(s, service); err != nil {
+				return nil, err
+			}
+			created = append(created, service)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		// The instances are already tracked so they're disposed with the scope.
+		return nil, err
+	}
+	return service, nil
+}
+
+// sameInstance reports whether a and b are the same instance without panicking for instances that
+// can't be compared.
+func sameInstance(a, b any) bool {
+	type_ := reflect.TypeOf(a)
+	return type_ == reflect.TypeOf(b) && (type_ == nil || type_.Comparable()) && a == b
+}
@@ -0,0 +1,128 @@
+package inject
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestRegisterDecorator(t *testing.T) {
+
+	wrap := func(suffix string) func(fooer, ServiceResolver) (fooer, error) {
+		return func(inner fooer, _ ServiceResolver) (fooer, error) {
+			return &namingFooer{name: inner.(*namingFooer).name + suffix}, nil
+		}
+	}
+
+	t.Run("decorators are applied in registration order", func(t *testing.T) {
+		services := ServiceCollection{}
+		RegisterDecorator(&services, wrap("+a"))
+		RegisterFunc[fooer](&services, Transient, func(ServiceResolver) (*namingFooer, error) {
+			return &namingFooer{name: "impl"}, nil
+		})
+		RegisterDecorator(&services, wrap("+b"))
+		provider, _ := services.Build()
+		resolved, err := Resolve[fooer](&provider)
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if name := resolved.(*namingFooer).name; name != "impl+a+b" {
+			t.Fatalf("expected %q; got %q", "impl+a+b", name)
+		}
+	})
+
+	t.Run("decorators are applied to every implementation", func(t *testing.T) {
+		services := ServiceCollection{}
+		for _, name := range []string{"x", "y"} {
+			RegisterFunc[fooer](&services, Transient, func(ServiceResolver) (*namingFooer, error) {
+				return &namingFooer{name: name}, nil
+			})
+		}
+		RegisterDecorator(&services, wrap("+a"))
+		provider, _ := services.Build()
+		resolved, _ := ResolveAll[fooer](&provider)
+		for i, expected := range []string{"x+a", "y+a"} {
+			if name := resolved[i].(*namingFooer).name; name != expected {
+				t.Fatalf("expected %q; got %q", expected, name)
+			}
+		}
+	})
+
+	t.Run("decorated singleton is created once", func(t *testing.T) {
+		var calls int
+		services := ServiceCollection{}
+		RegisterFunc[fooer](&services, Singleton, func(ServiceResolver) (*namingFooer, error) {
+			return &namingFooer{name: "impl"}, nil
+		})
+		RegisterDecorator(&services, func(inner fooer, r ServiceResolver) (fooer, error) {
+			calls++
+			return wrap("+a")(inner, r)
+		})
+		provider, _ := services.Build()
+		scope := provider.NewScope()
+		a, _ := Resolve[fooer](&provider)
+		b, _ := Resolve[fooer](&scope)
+		if a != b {
+			t.Fatalf("decorated singleton instances are distinct: %p %p", a, b)
+		}
+		if calls != 1 {
+			t.Fatalf("expected decorator to be called once; got %d", calls)
+		}
+	})
+
+	t.Run("decorator errors are returned", func(t *testing.T) {
+		expectedErr := errors.New("expected error")
+		services := ServiceCollection{}
+		RegisterFunc[fooer](&services, Transient, func(ServiceResolver) (*namingFooer, error) {
+			return &namingFooer{name: "impl"}, nil
+		})
+		RegisterDecorator(&services, func(fooer, ServiceResolver) (fooer, error) {
+			return nil, expectedErr
+		})
+		provider, _ := services.Build()
+		if _, err := Resolve[fooer](&provider); !errors.Is(err, expectedErr) {
+			t.Fatalf("expected %q; got %q", expectedErr, err)
+		}
+	})
+
+	t.Run("disposes the instances decorators wrap", func(t *testing.T) {
+		for _, lifetime := range []ServiceLifetime{Transient, Scoped, Singleton} {
+			log := &disposalLog{}
+			services := ServiceCollection{}
+			RegisterFunc[reader](&services, lifetime, func(ServiceResolver) (*closingReader, error) {
+				return &closingReader{closer{name: "inner", log: log}}, nil
+			})
+			// The first decorator isn't disposable and the second returns the instance it's given.
+			RegisterDecorator(&services, func(inner reader, _ ServiceResolver) (reader, error) {
+				return &prefixingReader{inner}, nil
+			})
+			RegisterDecorator(&services, func(inner reader, _ ServiceResolver) (reader, error) {
+				return inner, nil
+			})
+			provider, _ := services.Build()
+			scope := provider.NewScope()
+			if _, err := Resolve[reader](&scope); err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+			_ = scope.Close(context.Background())
+			_ = provider.Close(context.Background())
+			if expected := []string{"inner"}; !slices.Equal(log.names, expected) {
+				t.Fatalf("expected %v to dispose %v; got %v", lifetime, expected, log.names)
+			}
+		}
+	})
+}
+
+type closingReader struct {
+	closer
+}
+
+func (*closingReader) Read() string { return "inner" }
+
+// prefixingReader is a decorator that isn't disposable.
+type prefixingReader struct {
+	inner reader
+}
+
+func (r *prefixingReader) Read() string { return "prefixed " + r.inner.Read() }
@@ -0,0 +1,128 @@
+package inject
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+)
+
+// A singleton is a Singleton registration for a service.
+type singleton struct {
+	key          serviceKey
+	registration *serviceRegistration
+}
+
+// createSingletons creates every Singleton instance, in dependency order, and returns every error
+// that occurs. When parallel is true the Singletons that don't depend on each other are created
+// concurrently.
+func (provider *ServiceProvider) createSingletons(parallel bool) error {
+	var errs []error
+	var mu sync.Mutex
+	create := func(s singleton) {
+		if _, err := provider.resolveRegistration(context.Background(), nil, s.key, s.registration); err != nil {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		}
+	}
+	for _, level := range singletonLevels(provider.registrations) {
+		if !parallel {
+			for _, s := range level {
+				create(s)
+			}
+			continue
+		}
+		var wg sync.WaitGroup
+		for _, s := range level {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				create(s)
+			}()
+		}
+		wg.Wait()
+	}
+	return errors.Join(errs...)
+}
+
+// singletonLevels groups the Singleton registrations so that every Singleton is in a later level
+// than the Singletons it's known to depend on, directly or through other services. Singletons in
+// the same level don't depend on each other.
+func singletonLevels(registrations map[serviceKey][]*serviceRegistration) [][]singleton {
+	levels := make(map[*serviceRegistration]int)
+	var levelOf func(*serviceRegistration, []*serviceRegistration) int
+	// levelOf finds the level of a Singleton as one more than the highest level of the Singletons
+	// it depends on. The visiting registrations guard against cycles, which are reported by
+	// validate.
+	levelOf = func(registration *serviceRegistration, visiting []*serviceRegistration) int {
+		if level, ok := levels[registration]; ok {
+			return level
+		}
+		visiting = append(visiting, registration)
+		level := 0
+		for _, dependency := range registration.dependencies {
+			registered := registrations[underlying(dependency.serviceKey)]
+			if len(registered) == 0 {
+				continue
+			}
+			resolved := registered[len(registered)-1]
+			if slices.Contains(visiting, resolved) {
+				continue
+			}
+			dependencyLevel := levelOf(resolved, visiting)
+			if resolved.lifetime == Singleton {
+				dependencyLevel++
+			}
+			level = max(level, dependencyLevel)
+		}
+		// Only Singletons are saved since the level of other services isn't their own, it's just
+		// passed through to the Singletons that depend on them.
+		if registration.lifetime == Singleton {
+			levels[registration] = level
+		}
+		return level
+	}
+
+	var grouped [][]singleton
+	for _, key := range sortedKeys(registrations) {
+		for _, registration := range registrations[key] {
+			if registration.lifetime != Singleton {
+				continue
+			}
+			level := levelOf(registration, nil)
+			for len(grouped) <= level {
+				grouped = append(grouped, nil)
+			}
+			grouped[level] = append(grouped[level], singleton{key, registration})
+		}
+	}
+	return grouped
+}
+
+// validateByResolving resolves every registration from a throwaway ServiceProvider and scope and
+// returns every error that occurs.
+func validateByResolving(registrations map[serviceKey][]*serviceRegistration, options *buildOptions) error {
+	// The instances are thrown away so nobody needs to know they're resolved or disposed.
+	throwaway := *options
+	throwaway.onDispose = nil
+	throwaway.observers = nil
+	// The registered instances outlive the throwaway provider.
+	borrowed := make(map[serviceKey][]*serviceRegistration, len(registrations))
+	copies := make(map[*serviceRegistration]*serviceRegistration)
+	for key, registered := range registrations {
+		borrowed[key] = borrowInstances(registered, copies)
+	}
+	root := newServiceProvider(borrowed, &throwaway)
+	scope := root.NewScope()
+	var errs []error
+	for _, key := range sortedKeys(registrations) {
+		for _, registration := range borrowed[key] {
+			if _, err := scope.resolveRegistration(context.Background(), nil, key, registration); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	errs = append(errs, scope.Close(context.Background()), root.Close(context.Background()))
+	return errors.Join(errs...)
+}
@@ -0,0 +1,129 @@
+package inject
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+)
+
+func TestEagerSingletons(t *testing.T) {
+
+	for name, opt := range map[string]BuildOption{
+		"sequential": EagerSingletons(),
+		"parallel":   ParallelEagerSingletons(),
+	} {
+		t.Run(name, func(t *testing.T) {
+
+			t.Run("singletons are created by Build", func(t *testing.T) {
+				var created []string
+				services := ServiceCollection{}
+				RegisterConstructor[fooer](&services, Singleton, func(*structWithUnexportedFields) *assignableToFooer {
+					created = append(created, "fooer")
+					return &assignableToFooer{}
+				})
+				RegisterConstructor[*structWithUnexportedFields](&services, Singleton, func() *structWithUnexportedFields {
+					created = append(created, "struct")
+					return &structWithUnexportedFields{}
+				})
+				RegisterConstructor[*dependsOnFooer](&services, Transient, func() *dependsOnFooer {
+					created = append(created, "transient")
+					return &dependsOnFooer{}
+				})
+				if _, err := services.Build(opt); err != nil {
+					t.Fatalf("unexpected error from ServiceCollection.Build: %q", err)
+				}
+				if expected := []string{"struct", "fooer"}; !slices.Equal(created, expected) {
+					t.Fatalf("expected %v; got %v", expected, created)
+				}
+			})
+
+			t.Run("every error is returned and created singletons are closed", func(t *testing.T) {
+				errA, errB := errors.New("a"), errors.New("b")
+				log := &disposalLog{}
+				services := ServiceCollection{}
+				RegisterFunc[io.Closer](&services, Singleton, func(ServiceResolver) (*closer, error) {
+					return &closer{name: "closer", log: log}, nil
+				})
+				RegisterFunc[fooer](&services, Singleton, func(ServiceResolver) (*assignableToFooer, error) {
+					return nil, errA
+				})
+				RegisterFunc[*structWithUnexportedFields](&services, Singleton, func(ServiceResolver) (*structWithUnexportedFields, error) {
+					return nil, errB
+				})
+				_, err := services.Build(opt)
+				if !errors.Is(err, errA) || !errors.Is(err, errB) {
+					t.Fatalf("expected errors %q and %q; got %q", errA, errB, err)
+				}
+				if !slices.Equal(log.names, []string{"closer"}) {
+					t.Fatalf("expected created singletons to be closed; got %v", log.names)
+				}
+			})
+		})
+	}
+
+	t.Run("singletons are ordered after their dependencies", func(t *testing.T) {
+		services := ServiceCollection{}
+		RegisterType(&services, Singleton, &dependsOnDependsOnFooer{})
+		RegisterType(&services, Transient, &dependsOnFooer{})
+		RegisterType[fooer](&services, Singleton, &assignableToFooer{})
+		RegisterType(&services, Singleton, &structWithUnexportedFields{})
+		levels := singletonLevels(services.registrations)
+		var actual [][]string
+		for _, level := range levels {
+			var keys []string
+			for _, s := range level {
+				keys = append(keys, s.key.String())
+			}
+			actual = append(actual, keys)
+		}
+		expected := [][]string{
+			{"*inject.structWithUnexportedFields", "inject.fooer"},
+			{"*inject.dependsOnDependsOnFooer"},
+		}
+		if len(actual) != len(expected) || !slices.Equal(actual[0], expected[0]) || !slices.Equal(actual[1], expected[1]) {
+			t.Fatalf("expected %v; got %v", expected, actual)
+		}
+	})
+}
+
+func TestValidateOnBuild(t *testing.T) {
+
+	t.Run("every construction error is returned", func(t *testing.T) {
+		errA, errB := errors.New("a"), errors.New("b")
+		services := ServiceCollection{}
+		RegisterFunc[fooer](&services, Scoped, func(ServiceResolver) (*assignableToFooer, error) {
+			return nil, errA
+		})
+		RegisterFunc[*structWithUnexportedFields](&services, Transient, func(ServiceResolver) (*structWithUnexportedFields, error) {
+			return nil, errB
+		})
+		_, err := services.Build(ValidateOnBuild())
+		if !errors.Is(err, errA) || !errors.Is(err, errB) {
+			t.Fatalf("expected errors %q and %q; got %q", errA, errB, err)
+		}
+	})
+
+	t.Run("throwaway instances are closed and not reused", func(t *testing.T) {
+		log := &disposalLog{}
+		services := ServiceCollection{}
+		RegisterFunc[io.Closer](&services, Singleton, func(ServiceResolver) (*closer, error) {
+			return &closer{name: "singleton", log: log}, nil
+		})
+		RegisterFunc[Disposer](&services, Scoped, func(ServiceResolver) (*disposer, error) {
+			return &disposer{closer{name: "scoped", log: log}}, nil
+		})
+		provider, err := services.Build(ValidateOnBuild())
+		if err != nil {
+			t.Fatalf("unexpected error from ServiceCollection.Build: %q", err)
+		}
+		if expected := []string{"scoped.Dispose", "singleton"}; !slices.Equal(log.names, expected) {
+			t.Fatalf("expected %v; got %v", expected, log.names)
+		}
+		if _, err := Resolve[io.Closer](&provider); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		provider.Close(context.Background())
+	})
+}
@@ -0,0 +1,89 @@
+package inject
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// An injectedField is an exported struct field that is initialized with a resolved service.
+type injectedField struct {
+	index    int
+	name     string
+	key      serviceKey
+	optional bool
+}
+
+// getInjectedFields finds the exported fields of the given struct type that should be injected,
+// which are the ones with `inject` tags other than `inject:"-"`.
+func getInjectedFields(type_ reflect.Type) ([]injectedField, error) {
+	var fields []injectedField
+	for i := range type_.NumField() {
+		field := type_.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag, ok := field.Tag.Lookup("inject")
+		if !ok || tag == "-" {
+			continue
+		}
+		injected := injectedField{
+			index: i,
+			name:  field.Name,
+			key:   keyFor(field.Type),
+		}
+		for _, option := range strings.Split(tag, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(option), "=")
+			switch name {
+			case "":
+			case "key":
+				injected.key.key = value
+			case "optional":
+				injected.optional = true
+			default:
+				return nil, fmt.Errorf("unknown option %q in inject tag of field %v.%s", name, type_, field.Name)
+			}
+		}
+		fields = append(fields, injected)
+	}
+	return fields, nil
+}
+
+// injectFields initializes the given fields of v, which must be an addressable struct value, with
+// services resolved from the given resolver.
+func injectFields(resolver ServiceResolver, v reflect.Value, fields []injectedField) error {
+	for _, field := range fields {
+		service, err := resolveKey(resolver, field.key)
+		if err != nil {
+			if field.optional && isNotRegistered(err, field.key) {
+				continue
+			}
+			return err
+		}
+		if service == nil {
+			continue
+		}
+		value := reflect.ValueOf(service)
+		target := v.Field(field.index)
+		if !value.Type().AssignableTo(target.Type()) {
+			return fmt.Errorf("ServiceResolver returned %v when %v was requested", value.Type(), field.key)
+		}
+		target.Set(value)
+	}
+	return nil
+}
+
+func fieldDependencies(fields []injectedField) []dependency {
+	if len(fields) == 0 {
+		return nil
+	}
+	dependencies := make([]dependency, len(fields))
+	for i, field := range fields {
+		dependencies[i] = dependency{
+			serviceKey: field.key,
+			field:      field.name,
+			optional:   field.optional,
+		}
+	}
+	return dependencies
+}
@@ -0,0 +1,223 @@
+package inject
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// A Graph describes the registrations in a [ServiceCollection] and the dependencies between them.
+// It can be written as Graphviz DOT with [Graph.WriteDOT] to render it, or as JSON with
+// [Graph.WriteJSON] to compare it between versions of an application.
+type Graph struct {
+	Services []GraphService `json:"services"`
+}
+
+// A GraphService describes a single registration in a [Graph].
+type GraphService struct {
+	Service        string          `json:"service"`
+	Key            string          `json:"key,omitempty"`
+	Implementation string          `json:"implementation"`
+	Lifetime       ServiceLifetime `json:"lifetime"`
+	// Type is the package-qualified name of the service type, which unlike Service can't be shared
+	// by types from different packages.
+	Type string `json:"type"`
+	// Module is the name of the [Module] that made the registration, if any.
+	Module string `json:"module,omitempty"`
+	// Dependencies are the services the registration is known to depend on. Registrations made
+	// with funcs, e.g. with [RegisterFunc], may depend on services that aren't listed.
+	Dependencies []GraphDependency `json:"dependencies,omitempty"`
+}
+
+// A GraphDependency describes a service that a [GraphService] depends on.
+type GraphDependency struct {
+	Service string `json:"service"`
+	// Type is the package-qualified name of the service type, like [GraphService.Type].
+	Type string `json:"type"`
+	Key  string `json:"key,omitempty"`
+	// Field is the name of the struct field the dependency is injected into, if any.
+	Field string `json:"field,omitempty"`
+	// Optional is whether the dependency may be missing.
+	Optional bool `json:"optional,omitempty"`
+	// Kind is "Lazy", "Factory", or "Optional" when the dependency is a [Lazy], [Factory], or
+	// [Optional] of the service, which is provided without being registered, and empty when the
+	// service itself is injected.
+	Kind string `json:"kind,omitempty"`
+}
+
+// TypeName returns the package-qualified name of the given type, e.g.
+// "*github.com/ttd2089/stahp/inject.Graph" where [reflect.Type.String] returns "*inject.Graph", so
+// that types from different packages with the same name can be told apart.
+func TypeName(type_ reflect.Type) string {
+	if type_ == nil {
+		return "<nil>"
+	}
+	if type_.Name() != "" {
+		if type_.PkgPath() == "" {
+			return type_.Name()
+		}
+		return type_.PkgPath() + "." + type_.Name()
+	}
+	switch type_.Kind() {
+	case reflect.Pointer:
+		return "*" + TypeName(type_.Elem())
+	case reflect.Slice:
+		return "[]" + TypeName(type_.Elem())
+	case reflect.Array:
+		return fmt.Sprintf("[%d]%s", type_.Len(), TypeName(type_.Elem()))
+	case reflect.Map:
+		return fmt.Sprintf("map[%s]%s", TypeName(type_.Key()), TypeName(type_.Elem()))
+	case reflect.Chan:
+		switch type_.ChanDir() {
+		case reflect.RecvDir:
+			return "<-chan " + TypeName(type_.Elem())
+		case reflect.SendDir:
+			return "chan<- " + TypeName(type_.Elem())
+		}
+		return "chan " + TypeName(type_.Elem())
+	}
+	return type_.String()
+}
+
+// Graph describes the registrations in the target [ServiceCollection]. Services are ordered by
+// name and key, and registrations for the same service are in the order they were made, so the
+// Graph of a ServiceCollection is the same every time it's built.
+func (services *ServiceCollection) Graph() Graph {
+	if services == nil {
+		return Graph{}
+	}
+	services = services.target()
+	services.mu.Lock()
+	defer services.mu.Unlock()
+	graph := Graph{Services: []GraphService{}}
+	for _, key := range sortedKeys(services.registrations) {
+		for _, registration := range services.registrations[key] {
+			node := GraphService{
+				Service:        key.type_.String(),
+				Key:            key.key,
+				Implementation: registration.implType.String(),
+				Lifetime:       registration.lifetime,
+				Type:           TypeName(key.type_),
+				Module:         registration.module,
+			}
+			for _, dependency := range registration.dependencies {
+				node.Dependencies = append(node.Dependencies, services.describeDependency(dependency))
+			}
+			graph.Services = append(graph.Services, node)
+		}
+	}
+	return graph
+}
+
+// describeDependency describes the given dependency for a [Graph]. Lazy, Factory, and Optional
+// dependencies are described as the services they provide, unless the wrapper type itself is
+// registered.
+func (services *ServiceCollection) describeDependency(dependency dependency) GraphDependency {
+	key, kind := dependency.serviceKey, ""
+	if optional, ok := getOptional(key); ok {
+		key, kind = keyFor(optional.dependency()), "Optional"
+	} else if binder, ok := getBinder(key); ok && len(services.registrations[key]) == 0 {
+		kind, _, _ = strings.Cut(key.type_.Name(), "[")
+		key = keyFor(binder.dependency())
+	}
+	return GraphDependency{
+		Service:  key.type_.String(),
+		Type:     TypeName(key.type_),
+		Key:      key.key,
+		Field:    dependency.field,
+		Optional: dependency.optional || kind == "Optional",
+		Kind:     kind,
+	}
+}
+
+// WriteJSON writes the [Graph] to w as indented JSON.
+func (graph Graph) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(graph)
+}
+
+// WriteDOT writes the [Graph] to w in the Graphviz DOT language. Each registration is a node and
+// each dependency is an edge to the registration that resolves it. Lazy, Factory, and Optional
+// dependencies are drawn as dotted edges labeled with their kind. Dependencies on services that
+// aren't registered are drawn as dashed nodes.
+func (graph Graph) WriteDOT(w io.Writer) error {
+	// Services are identified by their types rather than their names, which may be shared by types
+	// from different packages.
+	type service struct {
+		type_, key string
+	}
+	// The last registration for a service is the one that resolves it.
+	resolvedBy := make(map[service]string, len(graph.Services))
+	for i, node := range graph.Services {
+		resolvedBy[service{node.Type, node.Key}] = nodeID(i)
+	}
+
+	var sb strings.Builder
+	printf := func(format string, args ...any) {
+		fmt.Fprintf(&sb, format, args...)
+	}
+	printf("digraph services {\n")
+	printf("\tnode [shape=box];\n")
+	for i, node := range graph.Services {
+		printf("\t%s [label=%s];\n", nodeID(i), dotString(
+			describeService(node.Service, node.Key),
+			node.Implementation,
+			node.Lifetime.String(),
+		))
+	}
+	missing := make(map[service]string)
+	for i, node := range graph.Services {
+		for _, dependency := range node.Dependencies {
+			target := service{dependency.Type, dependency.Key}
+			id, ok := resolvedBy[target]
+			if !ok {
+				if id, ok = missing[target]; !ok {
+					id = fmt.Sprintf("missing%d", len(missing))
+					missing[target] = id
+					printf("\t%s [label=%s, style=dashed];\n", id, dotString(describeService(dependency.Service, dependency.Key)))
+				}
+			}
+			var label []string
+			if dependency.Field != "" {
+				label = append(label, dependency.Field)
+			}
+			if dependency.Kind != "" {
+				label = append(label, dependency.Kind)
+			}
+			switch {
+			case dependency.Kind != "":
+				printf("\t%s -> %s [label=%s, style=dotted];\n", nodeID(i), id, dotString(label...))
+			case len(label) > 0:
+				printf("\t%s -> %s [label=%s];\n", nodeID(i), id, dotString(label...))
+			default:
+				printf("\t%s -> %s;\n", nodeID(i), id)
+			}
+		}
+	}
+	printf("}\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func nodeID(i int) string {
+	return fmt.Sprintf("n%d", i)
+}
+
+func describeService(service, key string) string {
+	if key == "" {
+		return service
+	}
+	return fmt.Sprintf("%s[key=%s]", service, key)
+}
+
+// dotString quotes the given lines as a single DOT string.
+func dotString(lines ...string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	for i, line := range lines {
+		lines[i] = replacer.Replace(line)
+	}
+	return `"` + strings.Join(lines, `\n`) + `"`
+}
@@ -0,0 +1,138 @@
+package inject
+
+import (
+	"bytes"
+	"encoding/json"
+	htmltemplate "html/template"
+	"reflect"
+	"strings"
+	"testing"
+	texttemplate "text/template"
+)
+
+func TestGraph(t *testing.T) {
+
+	newServices := func() *ServiceCollection {
+		services := &ServiceCollection{}
+		RegisterType(services, Transient, &structWithInjectedFields{})
+		RegisterType[fooer](services, Singleton, &assignableToFooer{})
+		return services
+	}
+
+	t.Run("describes registrations and dependencies", func(t *testing.T) {
+		expected := Graph{
+			Services: []GraphService{
+				{
+					Service:        "*inject.structWithInjectedFields",
+					Implementation: "*inject.structWithInjectedFields",
+					Lifetime:       Transient,
+					Type:           "*github.com/ttd2089/stahp/inject.structWithInjectedFields",
+					Dependencies: []GraphDependency{
+						{
+							Service: "inject.fooer",
+							Type:    "github.com/ttd2089/stahp/inject.fooer",
+							Field:   "Fooer",
+						},
+						{
+							Service: "*inject.structWithUnexportedFields",
+							Type:    "*github.com/ttd2089/stahp/inject.structWithUnexportedFields",
+							Key:     "replica",
+							Field:   "Replica",
+						},
+					},
+				},
+				{
+					Service:        "inject.fooer",
+					Implementation: "*inject.assignableToFooer",
+					Lifetime:       Singleton,
+					Type:           "github.com/ttd2089/stahp/inject.fooer",
+				},
+			},
+		}
+		if actual := newServices().Graph(); !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("expected %+v; got %+v", expected, actual)
+		}
+	})
+
+	t.Run("round trips through JSON", func(t *testing.T) {
+		expected := newServices().Graph()
+		var buf bytes.Buffer
+		if err := expected.WriteJSON(&buf); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if !strings.Contains(buf.String(), `"lifetime": "Singleton"`) {
+			t.Fatalf("expected lifetime names in JSON; got %s", buf.String())
+		}
+		var actual Graph
+		if err := json.Unmarshal(buf.Bytes(), &actual); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("expected %+v; got %+v", expected, actual)
+		}
+	})
+
+	t.Run("writes DOT", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := newServices().Graph().WriteDOT(&buf); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		expected := `digraph services {
+	node [shape=box];
+	n0 [label="*inject.structWithInjectedFields\n*inject.structWithInjectedFields\nTransient"];
+	n1 [label="inject.fooer\n*inject.assignableToFooer\nSingleton"];
+	n0 -> n1 [label="Fooer"];
+	missing0 [label="*inject.structWithUnexportedFields[key=replica]", style=dashed];
+	n0 -> missing0 [label="Replica"];
+}
+`
+		if actual := buf.String(); actual != expected {
+			t.Fatalf("expected:\n%s\ngot:\n%s", expected, actual)
+		}
+	})
+
+	t.Run("draws Lazy, Factory, and Optional dependencies as edges to the services they provide", func(t *testing.T) {
+		services := &ServiceCollection{}
+		RegisterType(services, Transient, &structWithLazyFields{})
+		RegisterConstructor[*withOptionalParam](services, Transient, newWithOptionalParam)
+		RegisterType(services, Transient, &structWithUnexportedFields{})
+		var buf bytes.Buffer
+		if err := services.Graph().WriteDOT(&buf); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		expected := `digraph services {
+	node [shape=box];
+	n0 [label="*inject.structWithLazyFields\n*inject.structWithLazyFields\nTransient"];
+	n1 [label="*inject.structWithUnexportedFields\n*inject.structWithUnexportedFields\nTransient"];
+	n2 [label="*inject.withOptionalParam\n*inject.withOptionalParam\nTransient"];
+	n0 -> n1 [label="Lazy\nLazy", style=dotted];
+	n0 -> n1 [label="Factory\nFactory", style=dotted];
+	missing0 [label="*inject.optionalCache", style=dashed];
+	n2 -> missing0 [label="Optional", style=dotted];
+}
+`
+		if actual := buf.String(); actual != expected {
+			t.Fatalf("expected:\n%s\ngot:\n%s", expected, actual)
+		}
+	})
+
+	t.Run("distinguishes types with the same name from different packages", func(t *testing.T) {
+		services := &ServiceCollection{}
+		RegisterInstance(services, texttemplate.New("text"))
+		RegisterInstance(services, htmltemplate.New("html"))
+		graph := services.Graph()
+		if len(graph.Services) != 2 {
+			t.Fatalf("expected 2 services; got %+v", graph.Services)
+		}
+		if graph.Services[0].Type == graph.Services[1].Type {
+			t.Fatalf("expected distinct types; got %q", graph.Services[0].Type)
+		}
+		var buf bytes.Buffer
+		if err := graph.WriteDOT(&buf); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if !strings.Contains(buf.String(), "n1 [") {
+			t.Fatalf("expected a node per service; got:\n%s", buf.String())
+		}
+	})
+}
@@ -0,0 +1,158 @@
+package inject
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// DefaultShutdownTimeout is how long a [Host] waits for its HTTP server and hosted services to stop
+// when no ShutdownTimeout is set.
+const DefaultShutdownTimeout = 30 * time.Second
+
+// A Host runs an application made of services: it builds the [ServiceProvider], starts the
+// [HostedService] instances, serves HTTP requests, and when the process is asked to stop, with
+// SIGTERM or an interrupt, it stops everything in the reverse order and closes the
+// ServiceProvider.
+type Host struct {
+
+	// Services are the services of the application.
+	Services *ServiceCollection
+
+	// BuildOptions configure the [ServiceProvider] built from Services.
+	BuildOptions []BuildOption
+
+	// Addr is the TCP address the HTTP server listens on. See [http.Server].
+	Addr string
+
+	// Handler creates the handler for the HTTP server from the built [ServiceProvider], e.g. a
+	// mux of stahp routes wrapped with stahp.RequestScopes. When Handler is nil the Host runs
+	// only the hosted services.
+	Handler func(*ServiceProvider) (http.Handler, error)
+
+	// StartTimeout bounds how long the Host waits for the hosted services to start, after which
+	// the context given to their Start methods is cancelled. When it's zero there is no bound.
+	StartTimeout time.Duration
+
+	// ShutdownTimeout bounds how long the Host waits for the HTTP server and the hosted services
+	// to stop and the ServiceProvider to close. When it's zero [DefaultShutdownTimeout] is used.
+	ShutdownTimeout time.Duration
+}
+
+// Run runs the application until ctx is done, the process receives SIGTERM or an interrupt, or
+// the HTTP server fails. Hosted services are started in dependency order before the HTTP server
+// starts and stopped in reverse after it stops. If a hosted service fails to start then the hosted
+// services that were already started are stopped. Every error that occurs while starting, running,
+// or stopping is returned.
+func (host *Host) Run(ctx context.Context) error {
+	if host.Services == nil {
+		return errors.New("cannot run Host without a ServiceCollection")
+	}
+	provider, err := host.Services.Build(host.BuildOptions...)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	var errs []error
+	startCtx, cancelStart := host.startContext(ctx)
+	started, err := startHostedServices(startCtx, &provider)
+	cancelStart()
+	errs = append(errs, err)
+
+	var server *http.Server
+	failed := make(chan error, 1)
+	if err == nil && host.Handler != nil {
+		server, err = host.newServer(ctx, &provider)
+		errs = append(errs, err)
+	}
+	if err == nil {
+		if server != nil {
+			go func() {
+				failed <- server.ListenAndServe()
+			}()
+		}
+		select {
+		case err := <-failed:
+			errs = append(errs, err)
+			server = nil
+		case <-ctx.Done():
+		}
+	}
+
+	// The run context is done by now but shutting down still needs time to do its work.
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), host.shutdownTimeout())
+	defer cancel()
+	if server != nil {
+		errs = append(errs, server.Shutdown(shutdownCtx))
+		if err := <-failed; !errors.Is(err, http.ErrServerClosed) {
+			errs = append(errs, err)
+		}
+	}
+	for i := len(started) - 1; i >= 0; i-- {
+		if err := started[i].Stop(shutdownCtx); err != nil {
+			errs = append(errs, fmt.Errorf("stopping hosted service %T: %w", started[i], err))
+		}
+	}
+	errs = append(errs, provider.Close(shutdownCtx))
+	return errors.Join(errs...)
+}
+
+func (host *Host) startContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if host.StartTimeout == 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, host.StartTimeout)
+}
+
+func (host *Host) shutdownTimeout() time.Duration {
+	if host.ShutdownTimeout == 0 {
+		return DefaultShutdownTimeout
+	}
+	return host.ShutdownTimeout
+}
+
+// newServer creates the HTTP server with the handler created from the given provider. Requests
+// are served with a context that isn't cancelled when ctx is so that they can finish while the
+// server shuts down.
+func (host *Host) newServer(ctx context.Context, provider *ServiceProvider) (*http.Server, error) {
+	handler, err := host.Handler(provider)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Server{
+		Addr:        host.Addr,
+		Handler:     handler,
+		BaseContext: func(net.Listener) context.Context { return context.WithoutCancel(ctx) },
+	}, nil
+}
+
+// startHostedServices resolves and starts the hosted services in dependency order, returning the
+// ones that were started. If any fails to resolve or start then the rest are not started.
+func startHostedServices(ctx context.Context, provider *ServiceProvider) ([]HostedService, error) {
+	key := keyFor(hostedServiceType)
+	var started []HostedService
+	for _, registration := range hostedServiceOrder(provider.registrations) {
+		resolved, err := provider.resolveRegistration(ctx, nil, key, registration)
+		if err != nil {
+			return started, err
+		}
+		hosted, ok := resolved.(HostedService)
+		if !ok {
+			return started, fmt.Errorf("ServiceResolver returned %T when %v was requested", resolved, hostedServiceType)
+		}
+		if err := hosted.Start(ctx); err != nil {
+			return started, fmt.Errorf("starting hosted service %T: %w", hosted, err)
+		}
+		started = append(started, hosted)
+	}
+	return started, nil
+}
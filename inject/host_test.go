@@ -0,0 +1,245 @@
+package inject
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+type hostedLog struct {
+	events []string
+}
+
+type hostedWorker struct {
+	Log *hostedLog `inject:""`
+}
+
+func (w *hostedWorker) Start(context.Context) error {
+	w.Log.events = append(w.Log.events, "start worker")
+	return nil
+}
+
+func (w *hostedWorker) Stop(context.Context) error {
+	w.Log.events = append(w.Log.events, "stop worker")
+	return nil
+}
+
+type failingHostedService struct {
+	err error
+}
+
+func (s *failingHostedService) Start(context.Context) error {
+	return s.err
+}
+
+func (s *failingHostedService) Stop(context.Context) error {
+	return errors.New("stopped a service that did not start")
+}
+
+// A cancellingHostedService cancels the context a Host is run with once it's started.
+type cancellingHostedService struct {
+	cancel context.CancelFunc
+}
+
+func (s *cancellingHostedService) Start(context.Context) error {
+	s.cancel()
+	return nil
+}
+
+func (s *cancellingHostedService) Stop(context.Context) error {
+	return nil
+}
+
+// A slowHostedService doesn't start until the context is done.
+type slowHostedService struct{}
+
+func (*slowHostedService) Start(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (*slowHostedService) Stop(context.Context) error {
+	return errors.New("stopped a service that did not start")
+}
+
+// A closingHostedService is a hosted service that must also be disposed.
+type closingHostedService struct {
+	closer
+}
+
+func (*closingHostedService) Start(context.Context) error {
+	return nil
+}
+
+func (*closingHostedService) Stop(context.Context) error {
+	return nil
+}
+
+type hostedConsumer struct {
+	Log    *hostedLog    `inject:""`
+	Worker *hostedWorker `inject:""`
+}
+
+func (c *hostedConsumer) Start(context.Context) error {
+	c.Log.events = append(c.Log.events, "start consumer")
+	return nil
+}
+
+func (c *hostedConsumer) Stop(context.Context) error {
+	c.Log.events = append(c.Log.events, "stop consumer")
+	return nil
+}
+
+func TestHost(t *testing.T) {
+
+	newServices := func(log *hostedLog) *ServiceCollection {
+		services := &ServiceCollection{}
+		RegisterFunc[*hostedLog](services, Singleton, func(ServiceResolver) (*hostedLog, error) {
+			return log, nil
+		})
+		RegisterFunc[*hostedWorker](services, Singleton, func(resolver ServiceResolver) (*hostedWorker, error) {
+			log, err := Resolve[*hostedLog](resolver)
+			return &hostedWorker{Log: log}, err
+		})
+		RegisterType(services, Singleton, &hostedConsumer{})
+		// The consumer is registered first but depends on the worker.
+		RegisterHostedService[*hostedConsumer](services)
+		RegisterHostedService[*hostedWorker](services)
+		return services
+	}
+
+	t.Run("starts hosted services in dependency order and stops them in reverse", func(t *testing.T) {
+		log := &hostedLog{}
+		services := newServices(log)
+		ctx, cancel := context.WithCancel(context.Background())
+		RegisterFunc[*cancellingHostedService](services, Singleton, func(ServiceResolver) (*cancellingHostedService, error) {
+			return &cancellingHostedService{cancel}, nil
+		})
+		RegisterHostedService[*cancellingHostedService](services)
+		host := Host{Services: services}
+
+		if err := host.Run(ctx); err != nil {
+			t.Fatalf("expected nil; got %q", err)
+		}
+
+		expected := []string{"start worker", "start consumer", "stop consumer", "stop worker"}
+		if !slices.Equal(log.events, expected) {
+			t.Fatalf("expected %q; got %q", expected, log.events)
+		}
+	})
+
+	t.Run("stops started hosted services when one fails to start", func(t *testing.T) {
+		log := &hostedLog{}
+		startErr := errors.New("nope")
+		services := newServices(log)
+		RegisterFunc[*failingHostedService](services, Singleton, func(ServiceResolver) (*failingHostedService, error) {
+			return &failingHostedService{startErr}, nil
+		})
+		RegisterHostedService[*failingHostedService](services)
+		host := Host{Services: services}
+
+		err := host.Run(context.Background())
+
+		if !errors.Is(err, startErr) {
+			t.Fatalf("expected %q; got %q", startErr, err)
+		}
+		expected := []string{"start worker", "start consumer", "stop consumer", "stop worker"}
+		if !slices.Equal(log.events, expected) {
+			t.Fatalf("expected %q; got %q", expected, log.events)
+		}
+	})
+
+	t.Run("cancels starting hosted services after StartTimeout", func(t *testing.T) {
+		log := &hostedLog{}
+		services := newServices(log)
+		RegisterType(services, Singleton, &slowHostedService{})
+		RegisterHostedService[*slowHostedService](services)
+		host := Host{Services: services, StartTimeout: 10 * time.Millisecond}
+
+		err := host.Run(context.Background())
+
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected %q; got %q", context.DeadlineExceeded, err)
+		}
+		expected := []string{"start worker", "start consumer", "stop consumer", "stop worker"}
+		if !slices.Equal(log.events, expected) {
+			t.Fatalf("expected %q; got %q", expected, log.events)
+		}
+	})
+
+	t.Run("disposes hosted Singletons once", func(t *testing.T) {
+		for _, test := range []struct {
+			name string
+			opts []BuildOption
+		}{
+			{"without StrictLifetimes", nil},
+			{"with StrictLifetimes", []BuildOption{StrictLifetimes()}},
+		} {
+			t.Run(test.name, func(t *testing.T) {
+				disposals := &disposalLog{}
+				services := newServices(&hostedLog{})
+				RegisterFunc[*closingHostedService](services, Singleton, func(ServiceResolver) (*closingHostedService, error) {
+					return &closingHostedService{closer{name: "hosted", log: disposals}}, nil
+				})
+				RegisterHostedService[*closingHostedService](services)
+				ctx, cancel := context.WithCancel(context.Background())
+				RegisterFunc[*cancellingHostedService](services, Singleton, func(ServiceResolver) (*cancellingHostedService, error) {
+					return &cancellingHostedService{cancel}, nil
+				})
+				RegisterHostedService[*cancellingHostedService](services)
+				host := Host{Services: services, BuildOptions: test.opts}
+
+				if err := host.Run(ctx); err != nil {
+					t.Fatalf("expected nil; got %q", err)
+				}
+
+				if !slices.Equal(disposals.names, []string{"hosted"}) {
+					t.Fatalf("expected %q; got %q", []string{"hosted"}, disposals.names)
+				}
+			})
+		}
+	})
+
+	t.Run("returns error from Handler and stops hosted services", func(t *testing.T) {
+		log := &hostedLog{}
+		handlerErr := errors.New("no handler")
+		host := Host{
+			Services: newServices(log),
+			Handler: func(*ServiceProvider) (http.Handler, error) {
+				return nil, handlerErr
+			},
+		}
+
+		err := host.Run(context.Background())
+
+		if !errors.Is(err, handlerErr) {
+			t.Fatalf("expected %q; got %q", handlerErr, err)
+		}
+		expected := []string{"start worker", "start consumer", "stop consumer", "stop worker"}
+		if !slices.Equal(log.events, expected) {
+			t.Fatalf("expected %q; got %q", expected, log.events)
+		}
+	})
+
+	t.Run("returns error when HTTP server fails", func(t *testing.T) {
+		log := &hostedLog{}
+		host := Host{
+			Services: newServices(log),
+			Addr:     "not an address",
+			Handler: func(*ServiceProvider) (http.Handler, error) {
+				return http.NotFoundHandler(), nil
+			},
+		}
+
+		if err := host.Run(context.Background()); err == nil {
+			t.Fatalf("expected error; got nil")
+		}
+		expected := []string{"start worker", "start consumer", "stop consumer", "stop worker"}
+		if !slices.Equal(log.events, expected) {
+			t.Fatalf("expected %q; got %q", expected, log.events)
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package inject
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+)
+
+// A HostedService is a background service, such as a queue consumer or a periodic job, whose
+// lifecycle is managed by a [Host]. Hosted services are started before the Host starts serving
+// HTTP requests and stopped after it stops.
+type HostedService interface {
+
+	// Start starts the service. Start should return once the service is running, not when its
+	// work is done. The context is cancelled once the hosted services have started, when the Host
+	// is stopped, or if starting them takes longer than the StartTimeout of the Host.
+	Start(context.Context) error
+
+	// Stop stops the service, returning once it has stopped or the context is done.
+	Stop(context.Context) error
+}
+
+var hostedServiceType = reflect.TypeFor[HostedService]()
+
+// RegisterHostedService registers the service type Impl, which must itself be registered, e.g.
+// with [RegisterType] or [RegisterConstructor], as a [HostedService] to be started and stopped by a
+// [Host]. Impl should be registered as a [Singleton] so that the instance the Host starts is the
+// same instance that's stopped and the same instance other services depend on.
+//
+// Hosted services are started in dependency order: when the known dependencies of one hosted
+// service include another hosted service, the other hosted service is started first. Otherwise
+// they're started in the order they were registered. They are stopped in the reverse order.
+func RegisterHostedService[Impl HostedService](services *ServiceCollection) error {
+	if services == nil {
+		return errors.New("cannot register types to a nil ServiceProvider")
+	}
+	implKey := keyFor(reflect.TypeFor[Impl]())
+	return services.addRegistration(keyFor(hostedServiceType), serviceRegistration{
+		lifetime:     Transient,
+		implType:     implKey.type_,
+		dependencies: []dependency{{serviceKey: implKey}},
+		alias:        true,
+		factory: func(resolver ServiceResolver) (any, error) {
+			return resolveKey(resolver, implKey)
+		},
+	}, appendRegistration)
+}
+
+// hostedServiceOrder returns the registrations of the hosted services in the order they should
+// be started.
+func hostedServiceOrder(registrations map[serviceKey][]*serviceRegistration) []*serviceRegistration {
+	hosted := registrations[keyFor(hostedServiceType)]
+
+	// dependsOn reports whether the given registration depends, directly or indirectly, on the
+	// service key.
+	dependsOn := func(registration *serviceRegistration, target serviceKey) bool {
+		seen := make(map[serviceKey]bool)
+		pending := slices.Clone(registration.dependencies)
+		for len(pending) > 0 {
+			next := pending[len(pending)-1]
+			pending = pending[:len(pending)-1]
+			if next.serviceKey == target {
+				return true
+			}
+			if seen[next.serviceKey] {
+				continue
+			}
+			seen[next.serviceKey] = true
+			if registered := registrations[next.serviceKey]; len(registered) > 0 {
+				pending = append(pending, registered[len(registered)-1].dependencies...)
+			}
+		}
+		return false
+	}
+
+	// Hosted services registered with funcs instead of RegisterHostedService have unknown
+	// implementations so nothing can be known to depend on them.
+	implKey := func(registration *serviceRegistration) (serviceKey, bool) {
+		if len(registration.dependencies) != 1 {
+			return serviceKey{}, false
+		}
+		return registration.dependencies[0].serviceKey, true
+	}
+
+	// Repeatedly pick the first hosted service, in registration order, that doesn't depend on any
+	// hosted service that hasn't been picked yet. If there's a cycle, which Build reports, just
+	// pick the first remaining hosted service.
+	remaining := slices.Clone(hosted)
+	ordered := make([]*serviceRegistration, 0, len(hosted))
+	for len(remaining) > 0 {
+		pick := 0
+		for i, candidate := range remaining {
+			blocked := slices.ContainsFunc(remaining, func(other *serviceRegistration) bool {
+				key, ok := implKey(other)
+				return ok && other != candidate && dependsOn(candidate, key)
+			})
+			if !blocked {
+				pick = i
+				break
+			}
+		}
+		ordered = append(ordered, remaining[pick])
+		remaining = slices.Delete(remaining, pick, pick+1)
+	}
+	return ordered
+}
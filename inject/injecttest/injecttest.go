@@ -0,0 +1,105 @@
+// Package injecttest provides helpers for testing applications that wire their services with
+// package inject, e.g. to check that the production [inject.ServiceCollection] resolves every
+// handler with the expected lifetimes and releases what it creates.
+package injecttest
+
+import (
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/ttd2089/stahp/inject"
+)
+
+// AssertResolves resolves an instance of T from the given [inject.ServiceResolver], reporting an
+// error to t when it can't be resolved. The resolved instance is returned so that it can be
+// checked further.
+func AssertResolves[T any](t testing.TB, resolver inject.ServiceResolver) T {
+	t.Helper()
+	service, err := inject.Resolve[T](resolver)
+	if err != nil {
+		t.Errorf("expected %v to resolve; got %q", reflect.TypeFor[T](), err)
+	}
+	return service
+}
+
+// AssertLifetime reports an error to t unless T is registered in the given
+// [inject.ServiceCollection] and the registration that resolves it has the given lifetime.
+func AssertLifetime[T any](t testing.TB, services *inject.ServiceCollection, lifetime inject.ServiceLifetime) {
+	t.Helper()
+	name := reflect.TypeFor[T]().String()
+	type_ := inject.TypeName(reflect.TypeFor[T]())
+	var actual *inject.ServiceLifetime
+	// Registrations for the same service are in the order they were made and the last one is used.
+	for _, service := range services.Graph().Services {
+		if service.Type == type_ && service.Key == "" {
+			actual = &service.Lifetime
+		}
+	}
+	if actual == nil {
+		t.Errorf("expected %s to be registered as %v; it is not registered", name, lifetime)
+		return
+	}
+	if *actual != lifetime {
+		t.Errorf("expected %s to be registered as %v; got %v", name, lifetime, *actual)
+	}
+}
+
+// A DisposalRecorder records the instances disposed by the [inject.ServiceProvider] values built
+// with its [DisposalRecorder.Option].
+//
+//	disposals := &injecttest.DisposalRecorder{}
+//	provider, _ := services.Build(disposals.Option())
+//	repo := injecttest.AssertResolves[*SQLUserRepo](t, &provider)
+//	provider.Close(context.Background())
+//	injecttest.AssertDisposed(t, disposals, repo)
+type DisposalRecorder struct {
+	mu       sync.Mutex
+	disposed []any
+}
+
+// Option returns the [inject.BuildOption] which makes a ServiceProvider report the instances it
+// disposes to the DisposalRecorder.
+func (recorder *DisposalRecorder) Option() inject.BuildOption {
+	return inject.OnDispose(func(service any, _ error) {
+		recorder.mu.Lock()
+		defer recorder.mu.Unlock()
+		recorder.disposed = append(recorder.disposed, service)
+	})
+}
+
+// Disposed returns the instances that have been disposed in the order they were disposed.
+func (recorder *DisposalRecorder) Disposed() []any {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	return slices.Clone(recorder.disposed)
+}
+
+// AssertDisposed reports an error to t unless the given instance was disposed.
+func AssertDisposed(t testing.TB, recorder *DisposalRecorder, service any) {
+	t.Helper()
+	if !slices.ContainsFunc(recorder.Disposed(), same(service)) {
+		t.Errorf("expected the %T to be disposed; it was not", service)
+	}
+}
+
+// AssertNotDisposed reports an error to t if the given instance was disposed.
+func AssertNotDisposed(t testing.TB, recorder *DisposalRecorder, service any) {
+	t.Helper()
+	if slices.ContainsFunc(recorder.Disposed(), same(service)) {
+		t.Errorf("expected the %T not to be disposed; it was", service)
+	}
+}
+
+// same returns a func reporting whether a value is the given service. Values that can't be
+// compared are never the same.
+func same(service any) func(any) bool {
+	return func(disposed any) bool {
+		type_ := reflect.TypeOf(service)
+		if type_ == nil || reflect.TypeOf(disposed) != type_ || !type_.Comparable() {
+			return false
+		}
+		return disposed == service
+	}
+}
@@ -0,0 +1,129 @@
+package injecttest
+
+import (
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+	"testing"
+	texttemplate "text/template"
+
+	"github.com/ttd2089/stahp/inject"
+)
+
+// recordingT records the errors reported by the helpers instead of failing the test.
+type recordingT struct {
+	testing.TB
+	errors []string
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+type repo struct {
+	closed bool
+}
+
+func (r *repo) Close() error {
+	r.closed = true
+	return nil
+}
+
+type clock struct{}
+
+func TestAssertResolves(t *testing.T) {
+
+	t.Run("returns the resolved instance", func(t *testing.T) {
+		services := inject.ServiceCollection{}
+		inject.RegisterType(&services, inject.Scoped, &repo{})
+		provider, _ := services.Build()
+		scope := provider.NewScope()
+		recorder := &recordingT{}
+
+		if service := AssertResolves[*repo](recorder, &scope); service == nil {
+			t.Fatalf("expected instance; got nil")
+		}
+		if len(recorder.errors) != 0 {
+			t.Fatalf("expected no errors; got %q", recorder.errors)
+		}
+	})
+
+	t.Run("reports services that do not resolve", func(t *testing.T) {
+		services := inject.ServiceCollection{}
+		provider, _ := services.Build()
+		recorder := &recordingT{}
+
+		AssertResolves[*repo](recorder, &provider)
+
+		if len(recorder.errors) != 1 {
+			t.Fatalf("expected 1 error; got %q", recorder.errors)
+		}
+	})
+}
+
+func TestAssertLifetime(t *testing.T) {
+
+	services := inject.ServiceCollection{}
+	inject.RegisterType(&services, inject.Transient, &repo{})
+	inject.ReplaceType(&services, inject.Scoped, &repo{})
+	inject.RegisterInstance(&services, texttemplate.New("text"))
+	inject.RegisterFunc[*htmltemplate.Template](&services, inject.Transient, func(inject.ServiceResolver) (*htmltemplate.Template, error) {
+		return htmltemplate.New("html"), nil
+	})
+
+	tests := []struct {
+		name     string
+		assert   func(testing.TB)
+		expected int
+	}{
+		{"passes for the lifetime of the last registration", func(t testing.TB) {
+			AssertLifetime[*repo](t, &services, inject.Scoped)
+		}, 0},
+		{"reports other lifetimes", func(t testing.TB) {
+			AssertLifetime[*repo](t, &services, inject.Singleton)
+		}, 1},
+		{"reports services that are not registered", func(t testing.TB) {
+			AssertLifetime[*clock](t, &services, inject.Singleton)
+		}, 1},
+		{"distinguishes types with the same name from different packages", func(t testing.TB) {
+			AssertLifetime[*texttemplate.Template](t, &services, inject.Singleton)
+		}, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := &recordingT{}
+			test.assert(recorder)
+			if len(recorder.errors) != test.expected {
+				t.Fatalf("expected %d errors; got %q", test.expected, recorder.errors)
+			}
+		})
+	}
+}
+
+func TestDisposalRecorder(t *testing.T) {
+
+	services := inject.ServiceCollection{}
+	inject.RegisterType(&services, inject.Scoped, &repo{})
+	disposals := &DisposalRecorder{}
+	provider, _ := services.Build(disposals.Option())
+	scope := provider.NewScope()
+	disposed := inject.MustResolve[*repo](&scope)
+	other := provider.NewScope()
+	notDisposed := inject.MustResolve[*repo](&other)
+
+	_ = scope.Close(context.Background())
+
+	recorder := &recordingT{}
+	AssertDisposed(recorder, disposals, disposed)
+	AssertNotDisposed(recorder, disposals, notDisposed)
+	if len(recorder.errors) != 0 {
+		t.Fatalf("expected no errors; got %q", recorder.errors)
+	}
+	AssertDisposed(recorder, disposals, notDisposed)
+	AssertNotDisposed(recorder, disposals, disposed)
+	if len(recorder.errors) != 2 {
+		t.Fatalf("expected 2 errors; got %q", recorder.errors)
+	}
+}
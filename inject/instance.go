@@ -0,0 +1,98 @@
+package inject
+
+import (
+	"errors"
+	"reflect"
+)
+
+// An InstanceOption configures a registration made with [RegisterInstance].
+type InstanceOption func(*instanceOptions)
+
+type instanceOptions struct {
+	owned bool
+}
+
+// Owned makes the [ServiceProvider] take ownership of an instance given to [RegisterInstance] so
+// that it's disposed like the instances the ServiceProvider creates when the ServiceProvider is
+// closed, if it implements [Disposer] or [io.Closer].
+func Owned() InstanceOption {
+	return func(options *instanceOptions) {
+		options.owned = true
+	}
+}
+
+// RegisterInstance registers an instance that has already been created, e.g. a parsed config or a
+// *log.Logger, as the [Singleton] implementation of the service type Service. Registering multiple
+// implementations adds to the existing registrations like [RegisterFunc].
+//
+// The [ServiceProvider] didn't create the instance so it doesn't dispose it when it's closed unless
+// the [Owned] option is given, even when other registrations return it, e.g. funcs that resolve it;
+// otherwise releasing the instance is up to whoever created it.
+func RegisterInstance[Service any](services *ServiceCollection, instance Service, opts ...InstanceOption) error {
+	if services == nil {
+		return errors.New("cannot register types to a nil ServiceProvider")
+	}
+	var options instanceOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	implType := reflect.TypeFor[Service]()
+	if value := reflect.ValueOf(instance); value.IsValid() {
+		implType = value.Type()
+	}
+
+	return services.addRegistration(keyFor(reflect.TypeFor[Service]()), serviceRegistration{
+		lifetime: Singleton,
+		implType: implType,
+		factory: func(ServiceResolver) (any, error) {
+			return instance, nil
+		},
+		instance: true,
+		owned:    options.owned,
+	}, appendRegistration)
+}
+
+// disposes reports whether the [ServiceProvider] disposes the instances of the registration.
+func (registration *serviceRegistration) disposes() bool {
+	return !registration.alias && (!registration.instance || registration.owned)
+}
+
+// borrowInstances returns a copy of the given registrations in which the instances given to
+// [RegisterInstance] aren't owned, for providers that mustn't dispose them because they belong to
+// another provider or to nobody. The registrations already borrowed are reused from borrowed so
+// that registrations which share instances keep sharing them.
+func borrowInstances(
+	registrations []*serviceRegistration,
+	borrowed map[*serviceRegistration]*serviceRegistration,
+) []*serviceRegistration {
+	copies := make([]*serviceRegistration, len(registrations))
+	for i, registration := range registrations {
+		copies[i] = borrowInstance(registration, borrowed)
+	}
+	return copies
+}
+
+func borrowInstance(
+	registration *serviceRegistration,
+	borrowed map[*serviceRegistration]*serviceRegistration,
+) *serviceRegistration {
+	if existing, ok := borrowed[registration]; ok {
+		return existing
+	}
+	result := registration
+	switch {
+	case registration.decorated != nil:
+		if decorated := borrowInstance(registration.decorated, borrowed); decorated != registration.decorated {
+			copied := *registration
+			copied.decorated = decorated
+			result = &copied
+		}
+	case registration.instance && registration.owned:
+		copied := *registration
+		copied.owned = false
+		result = &copied
+	}
+	borrowed[registration] = result
+	return result
+}
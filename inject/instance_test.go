@@ -0,0 +1,156 @@
+package inject
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRegisterInstance(t *testing.T) {
+
+	t.Run("resolves the given instance", func(t *testing.T) {
+		services := ServiceCollection{}
+		instance := &closer{name: "instance"}
+		RegisterInstance[io.Closer](&services, instance)
+		provider, err := services.Build()
+		if err != nil {
+			t.Fatalf("unexpected error from ServiceCollection.Build: %q", err)
+		}
+		resolved, err := Resolve[io.Closer](&provider)
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if resolved != instance {
+			t.Fatalf("expected %p; got %p", instance, resolved)
+		}
+	})
+
+	t.Run("returns error for nil ServiceCollection", func(t *testing.T) {
+		if err := RegisterInstance[io.Closer](nil, &closer{}); err == nil {
+			t.Fatal("expected error; got <nil>")
+		}
+	})
+
+	t.Run("does not dispose instances it does not own", func(t *testing.T) {
+		log := &disposalLog{}
+		services := ServiceCollection{}
+		RegisterInstance(&services, &closer{name: "instance", log: log})
+		provider, err := services.Build(ValidateOnBuild(), EagerSingletons())
+		if err != nil {
+			t.Fatalf("unexpected error from ServiceCollection.Build: %q", err)
+		}
+		MustResolve[*closer](&provider)
+		if err := provider.Close(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if len(log.names) != 0 {
+			t.Fatalf("expected no disposals; got %v", log.names)
+		}
+	})
+
+	t.Run("does not dispose instances it does not own when other registrations return them", func(t *testing.T) {
+		log := &disposalLog{}
+		services := ServiceCollection{}
+		RegisterInstance(&services, &closer{name: "instance", log: log})
+		RegisterFunc[io.Closer](&services, Transient, func(r ServiceResolver) (*closer, error) {
+			return Resolve[*closer](r)
+		})
+		RegisterKeyed[*closer](&services, Scoped, "scoped", func(r ServiceResolver) (*closer, error) {
+			return Resolve[*closer](r)
+		})
+		provider, err := services.Build(ValidateOnBuild())
+		if err != nil {
+			t.Fatalf("unexpected error from ServiceCollection.Build: %q", err)
+		}
+		if len(log.names) != 0 {
+			t.Fatalf("expected no disposals after Build; got %v", log.names)
+		}
+		scope := provider.NewScope()
+		MustResolve[io.Closer](&scope)
+		MustResolveKeyed[*closer](&scope, "scoped")
+		if err := scope.Close(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		MustResolve[io.Closer](&provider)
+		if err := provider.Close(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if len(log.names) != 0 {
+			t.Fatalf("expected no disposals; got %v", log.names)
+		}
+	})
+
+	t.Run("disposes owned instances once", func(t *testing.T) {
+		log := &disposalLog{}
+		services := ServiceCollection{}
+		RegisterInstance(&services, &closer{name: "instance", log: log, err: errors.New("expected error")}, Owned())
+		provider, err := services.Build(ValidateOnBuild())
+		if err != nil {
+			t.Fatalf("unexpected error from ServiceCollection.Build: %q", err)
+		}
+		scope := provider.NewScope()
+		MustResolve[*closer](&scope)
+		_ = scope.Close(context.Background())
+		if len(log.names) != 0 {
+			t.Fatalf("expected no disposals before the ServiceProvider is closed; got %v", log.names)
+		}
+		if err := provider.Close(context.Background()); err == nil {
+			t.Fatal("expected error; got <nil>")
+		}
+		if expected := []string{"instance"}; !slices.Equal(log.names, expected) {
+			t.Fatalf("expected %v; got %v", expected, log.names)
+		}
+	})
+
+	t.Run("does not dispose borrowed instances resolved after the context is done", func(t *testing.T) {
+		log := &disposalLog{}
+		services := ServiceCollection{}
+		RegisterInstance(&services, &closer{name: "instance", log: log})
+		provider, err := services.Build()
+		if err != nil {
+			t.Fatalf("unexpected error from ServiceCollection.Build: %q", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+		<-ctx.Done()
+		registrations := provider.registrations[keyFor(reflect.TypeFor[*closer]())]
+		if _, err := invoke(ctx, &provider, nil, registrations[0]); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected %q; got %q", context.DeadlineExceeded, err)
+		}
+		if _, err := ResolveContext[*closer](ctx, &provider); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected %q; got %q", context.DeadlineExceeded, err)
+		}
+		if len(log.names) != 0 {
+			t.Fatalf("expected no disposals; got %v", log.names)
+		}
+	})
+
+	t.Run("overriding providers do not dispose owned instances", func(t *testing.T) {
+		log := &disposalLog{}
+		services := ServiceCollection{}
+		RegisterInstance(&services, &closer{name: "instance", log: log}, Owned())
+		provider, err := services.Build()
+		if err != nil {
+			t.Fatalf("unexpected error from ServiceCollection.Build: %q", err)
+		}
+		overridden, err := provider.Override(func(*ServiceCollection) error { return nil })
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if MustResolve[*closer](&overridden) != MustResolve[*closer](&provider) {
+			t.Fatal("expected the same instance")
+		}
+		_ = overridden.Close(context.Background())
+		if len(log.names) != 0 {
+			t.Fatalf("expected no disposals; got %v", log.names)
+		}
+		_ = provider.Close(context.Background())
+		if expected := []string{"instance"}; !slices.Equal(log.names, expected) {
+			t.Fatalf("expected %v; got %v", expected, log.names)
+		}
+	})
+}
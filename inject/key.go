@@ -0,0 +1,70 @@
+package inject
+
+import (
+	"errors"
+	"reflect"
+)
+
+// A Key is a typed handle to the registrations of the service type T, as returned by [Provide],
+// which resolves instances of T without naming the type again at every call site:
+//
+//	userRepo, err := inject.Provide[UserRepo](services, inject.Scoped, newSQLUserRepo)
+//	...
+//	repo, err := userRepo.Get(&scope)
+//
+// Keys returned by [ProvideKeyed] resolve the registrations made under their key. The zero Key
+// resolves the registrations of T without a key, however they were registered.
+type Key[T any] struct {
+	key string
+}
+
+// ID identifies the service the Key resolves.
+func (key Key[T]) ID() ServiceID {
+	return ServiceID{reflect.TypeFor[T](), key.key}
+}
+
+func (key Key[T]) String() string {
+	return key.ID().String()
+}
+
+// Get obtains an instance of T from the given [ServiceResolver] like [Resolve], or like
+// [ResolveKeyed] for keyed Keys.
+func (key Key[T]) Get(resolver ServiceResolver) (T, error) {
+	return ResolveKeyed[T](resolver, key.key)
+}
+
+// MustGet obtains an instance of T like [Key.Get] and panics when Get would return an error. The
+// panic value is a [ResolutionError] so that the service that couldn't be resolved, and the chain
+// of services that led to it, are reported.
+func (key Key[T]) MustGet(resolver ServiceResolver) T {
+	service, err := key.Get(resolver)
+	if err != nil {
+		var resolutionErr *ResolutionError
+		if !errors.As(err, &resolutionErr) {
+			err = &ResolutionError{Path: []ServiceID{key.ID()}, Err: err}
+		}
+		panic(err)
+	}
+	return service
+}
+
+// Provide registers a factory like [RegisterFunc] and returns a [Key] to resolve the service type
+// Service with.
+func Provide[Service any, Impl any](
+	services *ServiceCollection,
+	lifetime ServiceLifetime,
+	factory func(ServiceResolver) (Impl, error),
+) (Key[Service], error) {
+	return Key[Service]{}, RegisterFunc[Service](services, lifetime, factory)
+}
+
+// ProvideKeyed registers a factory under the given key like [RegisterKeyed] and returns a [Key] to
+// resolve it with.
+func ProvideKeyed[Service any, Impl any](
+	services *ServiceCollection,
+	lifetime ServiceLifetime,
+	key string,
+	factory func(ServiceResolver) (Impl, error),
+) (Key[Service], error) {
+	return Key[Service]{key: key}, RegisterKeyed[Service](services, lifetime, key, factory)
+}
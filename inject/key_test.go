@@ -0,0 +1,88 @@
+package inject
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestKey(t *testing.T) {
+
+	t.Run("Get resolves the provided service", func(t *testing.T) {
+		services := ServiceCollection{}
+		key, err := Provide[fooer](&services, Singleton, func(ServiceResolver) (*assignableToFooer, error) {
+			return &assignableToFooer{}, nil
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		provider, _ := services.Build()
+		resolved, err := key.Get(&provider)
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if resolved != MustResolve[fooer](&provider) {
+			t.Fatalf("expected the same instance as Resolve")
+		}
+	})
+
+	t.Run("keyed Keys resolve their registration", func(t *testing.T) {
+		services := ServiceCollection{}
+		primary, _ := ProvideKeyed[string](&services, Singleton, "primary", func(ServiceResolver) (string, error) {
+			return "primary", nil
+		})
+		replica, _ := ProvideKeyed[string](&services, Singleton, "replica", func(ServiceResolver) (string, error) {
+			return "replica", nil
+		})
+		provider, _ := services.Build()
+		if resolved := primary.MustGet(&provider); resolved != "primary" {
+			t.Fatalf("expected %q; got %q", "primary", resolved)
+		}
+		if resolved := replica.MustGet(&provider); resolved != "replica" {
+			t.Fatalf("expected %q; got %q", "replica", resolved)
+		}
+		if expected := (ServiceID{reflect.TypeFor[string](), "replica"}); replica.ID() != expected {
+			t.Fatalf("expected %v; got %v", expected, replica.ID())
+		}
+	})
+
+	t.Run("Provide returns registration errors", func(t *testing.T) {
+		if _, err := Provide[fooer](nil, Singleton, func(ServiceResolver) (*assignableToFooer, error) {
+			return nil, nil
+		}); err == nil {
+			t.Fatal("expected error; got <nil>")
+		}
+	})
+
+	t.Run("MustGet panics with the resolution path", func(t *testing.T) {
+		services := ServiceCollection{}
+		key, _ := Provide[fooer](&services, Transient, func(resolver ServiceResolver) (*assignableToFooer, error) {
+			_, err := Resolve[string](resolver)
+			return &assignableToFooer{}, err
+		})
+		provider, _ := services.Build()
+		defer func() {
+			var resolutionErr *ResolutionError
+			if err, _ := recover().(error); !errors.As(err, &resolutionErr) {
+				t.Fatalf("expected *ResolutionError; got %v", err)
+			}
+			expected := []ServiceID{key.ID(), {Type: reflect.TypeFor[string]()}}
+			if !slices.Equal(resolutionErr.Path, expected) {
+				t.Fatalf("expected %v; got %v", expected, resolutionErr.Path)
+			}
+		}()
+		key.MustGet(&provider)
+	})
+
+	t.Run("MustGet panics with a ResolutionError for other errors", func(t *testing.T) {
+		var key Key[fooer]
+		defer func() {
+			var resolutionErr *ResolutionError
+			if err, _ := recover().(error); !errors.As(err, &resolutionErr) {
+				t.Fatalf("expected *ResolutionError; got %v", err)
+			}
+		}()
+		key.MustGet(nil)
+	})
+}
@@ -0,0 +1,101 @@
+package inject
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+)
+
+// A binder is a value which the [ServiceProvider] provides without a registration by binding it to
+// the resolver that requested it, i.e. [Lazy] and [Factory].
+type binder interface {
+	// bind creates a new value of the binder's type which resolves services from the resolver.
+	bind(ServiceResolver) any
+	// dependency is the type of the service the bound value resolves.
+	dependency() reflect.Type
+}
+
+var binderType = reflect.TypeFor[binder]()
+
+// getBinder returns a binder for the requested service if its type is one that can be bound.
+func getBinder(key serviceKey) (binder, bool) {
+	if key.key != "" || key.type_ == nil || !key.type_.Implements(binderType) {
+		return nil, false
+	}
+	binder, ok := reflect.Zero(key.type_).Interface().(binder)
+	return binder, ok
+}
+
+// A Lazy defers resolving an instance of T until it's first needed. Services that are expensive to
+// create and only used on rare paths can be injected as a Lazy[T] field or constructor parameter
+// without being registered; the [ServiceProvider] provides a Lazy bound to the scope it was
+// resolved from, as long as T itself is registered. A Lazy is safe for concurrent use and copies of
+// a Lazy share the same instance. Getting a Scoped or Singleton T while the same resolution is
+// still creating it, e.g. from the constructor of one of its dependencies, returns
+// [ErrCircularDependency].
+type Lazy[T any] struct {
+	state *lazyState[T]
+}
+
+type lazyState[T any] struct {
+	mu       sync.Mutex
+	resolver ServiceResolver
+	resolved bool
+	service  T
+}
+
+// NewLazy creates a [Lazy] which resolves its instance from the given [ServiceResolver].
+func NewLazy[T any](resolver ServiceResolver) Lazy[T] {
+	return Lazy[T]{&lazyState[T]{resolver: resolver}}
+}
+
+// Get resolves the instance of T the first time it's called and returns the same instance every
+// time after that. Errors are not saved, so a Get that fails may be retried.
+func (lazy Lazy[T]) Get() (T, error) {
+	if lazy.state == nil {
+		var zero T
+		return zero, errors.New("cannot resolve instances from a Lazy that was not created by NewLazy or a ServiceProvider")
+	}
+	lazy.state.mu.Lock()
+	defer lazy.state.mu.Unlock()
+	if !lazy.state.resolved {
+		service, err := Resolve[T](lazy.state.resolver)
+		if err != nil {
+			return service, err
+		}
+		lazy.state.service = service
+		lazy.state.resolved = true
+		// The resolver may be a whole scope so don't keep it alive for longer than necessary.
+		lazy.state.resolver = nil
+	}
+	return lazy.state.service, nil
+}
+
+func (Lazy[T]) bind(resolver ServiceResolver) any {
+	return NewLazy[T](resolver)
+}
+
+func (Lazy[T]) dependency() reflect.Type {
+	return reflect.TypeFor[T]()
+}
+
+// A Factory resolves a new instance of T every time it's called. Code that needs to create many
+// instances of a [Transient] service can be injected with a Factory[T] field or constructor
+// parameter without it being registered; the [ServiceProvider] provides a Factory bound to the
+// scope it was resolved from, as long as T itself is registered.
+type Factory[T any] func() (T, error)
+
+// NewFactory creates a [Factory] which resolves instances from the given [ServiceResolver].
+func NewFactory[T any](resolver ServiceResolver) Factory[T] {
+	return func() (T, error) {
+		return Resolve[T](resolver)
+	}
+}
+
+func (Factory[T]) bind(resolver ServiceResolver) any {
+	return NewFactory[T](resolver)
+}
+
+func (Factory[T]) dependency() reflect.Type {
+	return reflect.TypeFor[T]()
+}
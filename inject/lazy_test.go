@@ -0,0 +1,153 @@
+package inject
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLazy(t *testing.T) {
+
+	t.Run("resolves on first Get and caches", func(t *testing.T) {
+		resolver := mockResolver{}
+		resolver.returns(&structWithUnexportedFields{}, nil)
+		lazy := NewLazy[*structWithUnexportedFields](&resolver)
+		if len(resolver.requestedTypes) != 0 {
+			t.Fatalf("expected no resolution before Get; got %v", resolver.requestedTypes)
+		}
+		a, _ := lazy.Get()
+		b, _ := lazy.Get()
+		if len(resolver.requestedTypes) != 1 {
+			t.Fatalf("expected a single resolution; got %v", resolver.requestedTypes)
+		}
+		if a != b {
+			t.Fatalf("expected the same instance; got %p %p", a, b)
+		}
+	})
+
+	t.Run("retries after error", func(t *testing.T) {
+		expectedErr := errors.New("expected error")
+		resolver := mockResolver{}
+		resolver.returns(nil, expectedErr)
+		resolver.returns(&structWithUnexportedFields{}, nil)
+		lazy := NewLazy[*structWithUnexportedFields](&resolver)
+		if _, err := lazy.Get(); !errors.Is(err, expectedErr) {
+			t.Fatalf("expected %q; got %q", expectedErr, err)
+		}
+		if _, err := lazy.Get(); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+	})
+
+	t.Run("zero value returns error", func(t *testing.T) {
+		var lazy Lazy[int]
+		if _, err := lazy.Get(); err == nil {
+			t.Fatal("expected error; got <nil>")
+		}
+	})
+}
+
+func TestInjectedBinders(t *testing.T) {
+
+	t.Run("fields are bound to the resolving scope", func(t *testing.T) {
+		services := ServiceCollection{}
+		RegisterType(&services, Scoped, &structWithUnexportedFields{})
+		RegisterType(&services, Transient, &structWithLazyFields{})
+		provider, err := services.Build()
+		if err != nil {
+			t.Fatalf("unexpected error from ServiceCollection.Build: %q", err)
+		}
+		scope := provider.NewScope()
+		resolved, err := Resolve[*structWithLazyFields](&scope)
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		fromLazy, _ := resolved.Lazy.Get()
+		fromFactory, _ := resolved.Factory()
+		fromScope, _ := Resolve[*structWithUnexportedFields](&scope)
+		if fromLazy != fromScope || fromFactory != fromScope {
+			t.Fatalf("expected instances from the resolving scope; got %p %p %p", fromLazy, fromFactory, fromScope)
+		}
+	})
+
+	t.Run("Build returns error when the bound service is not registered", func(t *testing.T) {
+		services := ServiceCollection{}
+		RegisterType(&services, Transient, &structWithLazyFields{})
+		if _, err := services.Build(); !errors.Is(err, ErrMissingDependency) {
+			t.Fatalf("expected %q; got %q", ErrMissingDependency, err)
+		}
+	})
+
+	t.Run("Lazy breaks dependency cycles", func(t *testing.T) {
+		services := ServiceCollection{}
+		RegisterType(&services, Scoped, &lazyCycleA{})
+		RegisterType(&services, Scoped, &lazyCycleB{})
+		provider, err := services.Build()
+		if err != nil {
+			t.Fatalf("unexpected error from ServiceCollection.Build: %q", err)
+		}
+		a, err := Resolve[*lazyCycleA](&provider)
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if fromLazy, _ := a.B.A.Get(); fromLazy != a {
+			t.Fatalf("expected %p; got %p", a, fromLazy)
+		}
+	})
+
+	t.Run("resolving a service through a Lazy while creating it returns error", func(t *testing.T) {
+		for _, lifetime := range []ServiceLifetime{Scoped, Singleton} {
+			t.Run(lifetime.String(), func(t *testing.T) {
+				services := ServiceCollection{}
+				RegisterFunc[*lazyCycleA](&services, lifetime, func(r ServiceResolver) (*lazyCycleA, error) {
+					b, err := Resolve[*lazyCycleB](r)
+					return &lazyCycleA{B: b}, err
+				})
+				RegisterConstructor[*lazyCycleB](&services, Transient, func(a Lazy[*lazyCycleA]) (*lazyCycleB, error) {
+					_, err := a.Get()
+					return &lazyCycleB{A: a}, err
+				})
+				provider, err := services.Build()
+				if err != nil {
+					t.Fatalf("unexpected error from ServiceCollection.Build: %q", err)
+				}
+				scope := provider.NewScope()
+				errs := make(chan error, 1)
+				go func() {
+					_, err := Resolve[*lazyCycleA](&scope)
+					errs <- err
+				}()
+				select {
+				case err := <-errs:
+					if !errors.Is(err, ErrCircularDependency) {
+						t.Fatalf("expected %q; got %q", ErrCircularDependency, err)
+					}
+				case <-time.After(time.Second):
+					t.Fatal("resolution is waiting for itself")
+				}
+				if _, err := Resolve[*lazyCycleA](&scope); !errors.Is(err, ErrCircularDependency) {
+					t.Fatalf("expected %q on retry; got %q", ErrCircularDependency, err)
+				}
+			})
+		}
+	})
+
+	t.Run("constructor parameters are bound", func(t *testing.T) {
+		services := ServiceCollection{}
+		RegisterType(&services, Transient, &structWithUnexportedFields{})
+		err := RegisterConstructor[fooer](&services, Transient, func(f Factory[*structWithUnexportedFields]) *assignableToFooer {
+			if _, err := f(); err != nil {
+				t.Errorf("unexpected error from Factory: %q", err)
+			}
+			return &assignableToFooer{}
+		})
+		if err != nil {
+			t.Fatalf("unexpected error from RegisterConstructor: %q", err)
+		}
+		provider, _ := services.Build()
+		if _, err := provider.Resolve(reflect.TypeFor[fooer]()); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+	})
+}
@@ -1,5 +1,7 @@
 package inject
 
+import "fmt"
+
 // A ServiceLifetime describes the instantiation semantics for a service provided by a
 // [ServiceProvider].
 type ServiceLifetime int
@@ -37,3 +39,23 @@ func (lifetime ServiceLifetime) String() string {
 	}
 	return "<unknown ServiceLifetime>"
 }
+
+// MarshalText encodes the lifetime as its name, e.g. for use in JSON.
+func (lifetime ServiceLifetime) MarshalText() ([]byte, error) {
+	switch lifetime {
+	case Transient, Scoped, Singleton:
+		return []byte(lifetime.String()), nil
+	}
+	return nil, fmt.Errorf("cannot marshal unknown ServiceLifetime %d", int(lifetime))
+}
+
+// UnmarshalText decodes a lifetime from its name.
+func (lifetime *ServiceLifetime) UnmarshalText(text []byte) error {
+	for _, candidate := range []ServiceLifetime{Transient, Scoped, Singleton} {
+		if string(text) == candidate.String() {
+			*lifetime = candidate
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown ServiceLifetime %q", text)
+}
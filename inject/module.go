@@ -0,0 +1,233 @@
+package inject
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// ErrModuleInstalled is returned by [ServiceCollection.Install] when a [Module] is installed more
+// than once, or when a different Module with the same name is installed.
+var ErrModuleInstalled = errors.New("module already installed")
+
+// A Module groups the registrations of a cluster of related services, e.g. everything a package
+// needs, so that an application can install them with a single call to
+// [ServiceCollection.Install].
+//
+//	var Users = &inject.Module{
+//		Name:      "users",
+//		DependsOn: []*inject.Module{storage.Module},
+//		Requires:  []inject.ServiceID{{Type: reflect.TypeFor[*slog.Logger]()}},
+//		Register: func(services *inject.ServiceCollection) error {
+//			return inject.RegisterConstructor[UserRepo](services, inject.Scoped, NewSQLUserRepo)
+//		},
+//	}
+type Module struct {
+
+	// Name identifies the Module in diagnostics. Names must be unique within a ServiceCollection.
+	Name string
+
+	// DependsOn are the Modules whose services this Module's services depend on. They are
+	// installed before this Module if they aren't already installed.
+	DependsOn []*Module
+
+	// Requires are the services this Module's services depend on which must be registered outside
+	// of any Module, e.g. by the application. [ServiceCollection.Build] reports the ones that
+	// aren't, even if another Module registers them; services provided by other Modules are
+	// depended on with DependsOn.
+	Requires []ServiceID
+
+	// Register makes the Module's registrations.
+	Register func(*ServiceCollection) error
+}
+
+// Install installs the given modules, and the modules they depend on, into the target
+// [ServiceCollection]. Each module is installed once: modules that are only depended on may be
+// depended on by any number of modules, but installing a module that has already been installed
+// returns [ErrModuleInstalled]. Registrations made by a module are attributed to it by
+// [ServiceCollection.Build] and [ServiceCollection.Graph].
+//
+// When installing a module fails, every change made by it and by the modules installed for it is
+// undone so that installing it can be retried. The changes can't be undone without losing the
+// changes made to the ServiceCollection from other goroutines in the meantime, if any, in which case
+// the module remains installed and the error says so.
+func (services *ServiceCollection) Install(modules ...*Module) error {
+	if services == nil {
+		return errors.New("cannot install modules into a nil ServiceCollection")
+	}
+	if services.installation == nil {
+		// Make the changes through a view that counts them.
+		services = &ServiceCollection{parent: services.target(), installation: &installation{}}
+	}
+	for _, module := range modules {
+		if module == nil {
+			return errors.New("cannot install nil Module")
+		}
+		if err := services.install(nil, module, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// An installation counts the changes made while installing modules so that a module's changes can
+// be told apart from changes made from other goroutines.
+type installation struct {
+	changes uint64
+}
+
+// A snapshot is the state of a ServiceCollection to return to when installing a module fails.
+type snapshot struct {
+	// changes and installed are the counts of the changes made to the ServiceCollection and by the
+	// installation when the snapshot was taken.
+	changes        uint64
+	installed      uint64
+	registrations  map[serviceKey][]*serviceRegistration
+	decorators     map[serviceKey][]decoratorFunc
+	configurations map[reflect.Type]anyConfiguration
+	// sources are the numbers of sources of the configurations, which are added to in place.
+	sources map[reflect.Type]int
+	modules map[string]*Module
+}
+
+func (services *ServiceCollection) snapshot(installation *installation) *snapshot {
+	state := &snapshot{
+		changes:        services.changes,
+		installed:      installation.changes,
+		registrations:  make(map[serviceKey][]*serviceRegistration, len(services.registrations)),
+		decorators:     make(map[serviceKey][]decoratorFunc, len(services.decorators)),
+		configurations: maps.Clone(services.configurations),
+		sources:        make(map[reflect.Type]int, len(services.configurations)),
+		modules:        maps.Clone(services.modules),
+	}
+	for key, registered := range services.registrations {
+		state.registrations[key] = slices.Clone(registered)
+	}
+	for key, decorators := range services.decorators {
+		state.decorators[key] = slices.Clone(decorators)
+	}
+	for type_, config := range services.configurations {
+		state.sources[type_] = config.sourceCount()
+	}
+	return state
+}
+
+// restore returns the ServiceCollection to the given snapshot.
+func (services *ServiceCollection) restore(state *snapshot) {
+	services.registrations = state.registrations
+	services.decorators = state.decorators
+	services.configurations = state.configurations
+	for type_, config := range services.configurations {
+		config.truncate(state.sources[type_])
+	}
+	services.modules = state.modules
+}
+
+// install installs the given module after the modules it depends on. The modules in path depend
+// on the module, and explicit is whether the module itself is being installed rather than one that
+// depends on it.
+func (services *ServiceCollection) install(path []string, module *Module, explicit bool) error {
+	if slices.Contains(path, module.Name) {
+		return fmt.Errorf("%w: modules %s", ErrCircularDependency, strings.Join(append(path, module.Name), " -> "))
+	}
+	// Claim the name before installing so that a module being installed from another goroutine
+	// isn't installed twice.
+	var state *snapshot
+	err := services.update(func(target *ServiceCollection) error {
+		if installed, ok := target.modules[module.Name]; ok {
+			if installed != module {
+				return fmt.Errorf("%w: a different module named %s", ErrModuleInstalled, module.Name)
+			}
+			if explicit {
+				return fmt.Errorf("%w: %s", ErrModuleInstalled, module.Name)
+			}
+			return nil
+		}
+		state = target.snapshot(services.installation)
+		if target.modules == nil {
+			target.modules = make(map[string]*Module)
+		}
+		target.modules[module.Name] = module
+		return nil
+	})
+	if err != nil || state == nil {
+		return err
+	}
+
+	path = append(slices.Clip(path), module.Name)
+	for _, dependency := range module.DependsOn {
+		if dependency == nil {
+			err = fmt.Errorf("module %s depends on nil Module", module.Name)
+		} else {
+			err = services.install(path, dependency, false)
+		}
+		if err != nil {
+			break
+		}
+	}
+	if err == nil && module.Register != nil {
+		// The module registers to a view of the ServiceCollection which attributes the
+		// registrations to it.
+		view := &ServiceCollection{
+			parent:       services.target(),
+			module:       module.Name,
+			installation: services.installation,
+		}
+		if err = module.Register(view); err != nil {
+			err = fmt.Errorf("installing module %s: %w", module.Name, err)
+		}
+	}
+	if err != nil {
+		// Undo the module's changes so that installing can be retried, unless the ServiceCollection
+		// was frozen in the meantime.
+		installed := services.installation.changes
+		_ = services.update(func(target *ServiceCollection) error {
+			// Every change since the snapshot, including the claim, was made by the installation.
+			if target.changes-state.changes != installed-state.installed+1 {
+				err = fmt.Errorf("%w; module %s remains installed because the ServiceCollection was changed concurrently", err, module.Name)
+				return nil
+			}
+			target.restore(state)
+			return nil
+		})
+	}
+	return err
+}
+
+// validateModules checks that the services required by the installed modules are registered
+// outside of any module.
+func validateModules(services *ServiceCollection) error {
+	names := make([]string, 0, len(services.modules))
+	for name := range services.modules {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	var errs []error
+	for _, name := range names {
+		for _, required := range services.modules[name].Requires {
+			key := serviceKey{required.Type, required.Key}
+			if !registeredOutsideModules(services.registrations, key) {
+				errs = append(errs, fmt.Errorf("%w: module %s requires %v which is not registered outside of a module",
+					ErrMissingDependency, name, key))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// registeredOutsideModules reports whether the given service is provided by a registration that
+// wasn't made by a module, like isRegistered.
+func registeredOutsideModules(registrations map[serviceKey][]*serviceRegistration, key serviceKey) bool {
+	if _, ok := getOptional(key); ok {
+		return true
+	}
+	if binder, ok := getBinder(key); ok && len(registrations[key]) == 0 {
+		key = keyFor(binder.dependency())
+	}
+	return slices.ContainsFunc(registrations[key], func(registration *serviceRegistration) bool {
+		return registration.module == ""
+	})
+}
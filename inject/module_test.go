@@ -0,0 +1,220 @@
+package inject
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInstall(t *testing.T) {
+
+	newModules := func() (fooers *Module, dependents *Module) {
+		fooers = &Module{
+			Name: "fooers",
+			Register: func(services *ServiceCollection) error {
+				return RegisterType[fooer](services, Singleton, &assignableToFooer{})
+			},
+		}
+		dependents = &Module{
+			Name:      "dependents",
+			DependsOn: []*Module{fooers},
+			Register: func(services *ServiceCollection) error {
+				return RegisterType(services, Transient, &dependsOnFooer{})
+			},
+		}
+		return fooers, dependents
+	}
+
+	t.Run("installs the modules a module depends on first", func(t *testing.T) {
+		fooers, dependents := newModules()
+		services := ServiceCollection{}
+
+		if err := services.Install(dependents); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if err := services.Install(fooers); !errors.Is(err, ErrModuleInstalled) {
+			t.Fatalf("expected %q; got %q", ErrModuleInstalled, err)
+		}
+		provider, err := services.Build()
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+
+		if _, err := Resolve[*dependsOnFooer](&provider); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+	})
+
+	t.Run("installs shared dependencies once", func(t *testing.T) {
+		fooers, dependents := newModules()
+		other := &Module{Name: "other", DependsOn: []*Module{fooers}}
+		services := ServiceCollection{}
+
+		if err := services.Install(dependents, other); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+
+		if len(services.registrations[keyFor(reflect.TypeFor[fooer]())]) != 1 {
+			t.Fatalf("expected fooers to be installed once")
+		}
+	})
+
+	t.Run("returns error for different modules with the same name", func(t *testing.T) {
+		fooers, _ := newModules()
+		services := ServiceCollection{}
+		_ = services.Install(fooers)
+
+		err := services.Install(&Module{Name: "other", DependsOn: []*Module{{Name: "fooers"}}})
+
+		if !errors.Is(err, ErrModuleInstalled) {
+			t.Fatalf("expected %q; got %q", ErrModuleInstalled, err)
+		}
+	})
+
+	t.Run("returns error for modules that depend on each other", func(t *testing.T) {
+		a := &Module{Name: "a"}
+		b := &Module{Name: "b", DependsOn: []*Module{a}}
+		a.DependsOn = []*Module{b}
+		services := ServiceCollection{}
+
+		err := services.Install(a)
+
+		if !errors.Is(err, ErrCircularDependency) || !strings.Contains(err.Error(), "a -> b -> a") {
+			t.Fatalf("expected %q with a -> b -> a; got %q", ErrCircularDependency, err)
+		}
+	})
+
+	t.Run("returns errors from Register with the module name", func(t *testing.T) {
+		expectedErr := errors.New("nope")
+		services := ServiceCollection{}
+
+		err := services.Install(&Module{Name: "broken", Register: func(*ServiceCollection) error {
+			return expectedErr
+		}})
+
+		if !errors.Is(err, expectedErr) || !strings.Contains(err.Error(), "broken") {
+			t.Fatalf("expected %q from module broken; got %q", expectedErr, err)
+		}
+	})
+
+	t.Run("undoes the changes of a failed install so that it can be retried", func(t *testing.T) {
+		fooers, _ := newModules()
+		type config struct{ Name string }
+		fail := true
+		flaky := &Module{
+			Name:      "flaky",
+			DependsOn: []*Module{fooers},
+			Register: func(services *ServiceCollection) error {
+				if err := RegisterType(services, Transient, &dependsOnFooer{}); err != nil {
+					return err
+				}
+				if err := ConfigureFunc(services, func(c *config) error {
+					c.Name += "flaky"
+					return nil
+				}); err != nil {
+					return err
+				}
+				if err := RegisterDecorator(services, func(inner fooer, _ ServiceResolver) (fooer, error) {
+					return inner, nil
+				}); err != nil {
+					return err
+				}
+				if fail {
+					return errors.New("not yet")
+				}
+				return nil
+			},
+		}
+		services := ServiceCollection{}
+		ConfigureFunc(&services, func(c *config) error {
+			c.Name += "app+"
+			return nil
+		})
+
+		if err := services.Install(flaky); err == nil {
+			t.Fatal("expected error; got <nil>")
+		}
+		if len(services.registrations[keyFor(reflect.TypeFor[*dependsOnFooer]())]) != 0 {
+			t.Fatalf("expected the registrations of the failed install to be removed")
+		}
+		fail = false
+		if err := services.Install(flaky); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+
+		provider, err := services.Build()
+		if err != nil {
+			t.Fatalf("unexpected error from ServiceCollection.Build: %q", err)
+		}
+		all, _ := ResolveAll[*dependsOnFooer](&provider)
+		resolved, _ := ResolveAll[fooer](&provider)
+		if len(all) != 1 || len(resolved) != 1 {
+			t.Fatalf("expected a single registration of each service; got %d and %d", len(all), len(resolved))
+		}
+		if options := MustResolve[Options[config]](&provider); options.Value().Name != "app+flaky" {
+			t.Fatalf("expected %q; got %q", "app+flaky", options.Value().Name)
+		}
+		if len(services.decorators[keyFor(reflect.TypeFor[fooer]())]) != 1 {
+			t.Fatalf("expected a single decorator")
+		}
+	})
+
+	t.Run("Build reports required services that are not registered", func(t *testing.T) {
+		services := ServiceCollection{}
+		_ = services.Install(&Module{
+			Name:     "needy",
+			Requires: []ServiceID{{Type: reflect.TypeFor[fooer]()}},
+		})
+
+		_, err := services.Build()
+
+		if !errors.Is(err, ErrMissingDependency) || !strings.Contains(err.Error(), "module needy requires inject.fooer") {
+			t.Fatalf("expected %q for module needy; got %q", ErrMissingDependency, err)
+		}
+	})
+
+	t.Run("Build requires required services to be registered outside of modules", func(t *testing.T) {
+		newServices := func() *ServiceCollection {
+			services := &ServiceCollection{}
+			_ = services.Install(&Module{
+				Name: "provider",
+				Register: func(services *ServiceCollection) error {
+					return RegisterType[fooer](services, Singleton, &assignableToFooer{})
+				},
+			}, &Module{
+				Name:     "needy",
+				Requires: []ServiceID{{Type: reflect.TypeFor[fooer]()}},
+			})
+			return services
+		}
+
+		_, err := newServices().Build()
+
+		if !errors.Is(err, ErrMissingDependency) || !strings.Contains(err.Error(), "module needy requires inject.fooer") {
+			t.Fatalf("expected %q for module needy; got %q", ErrMissingDependency, err)
+		}
+
+		services := newServices()
+		RegisterType[fooer](services, Singleton, &assignableToFooer{})
+		if _, err := services.Build(); err != nil {
+			t.Fatalf("unexpected error from ServiceCollection.Build: %q", err)
+		}
+	})
+
+	t.Run("Build attributes missing dependencies to modules", func(t *testing.T) {
+		_, dependents := newModules()
+		dependents.DependsOn = nil
+		services := ServiceCollection{}
+		_ = services.Install(dependents)
+
+		_, err := services.Build()
+
+		if !errors.Is(err, ErrMissingDependency) || !strings.Contains(err.Error(), "*inject.dependsOnFooer (module dependents)") {
+			t.Fatalf("expected %q for module dependents; got %q", ErrMissingDependency, err)
+		}
+		if module := services.Graph().Services[0].Module; module != "dependents" {
+			t.Fatalf("expected %q; got %q", "dependents", module)
+		}
+	})
+}
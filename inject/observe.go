@@ -0,0 +1,148 @@
+package inject
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// An Observer is notified by a [ServiceProvider] built with [Observe] before and after each
+// registration is resolved. Resolving a service notifies the Observer about its dependencies too,
+// between the notifications about the service itself. Observers are called synchronously from
+// every goroutine resolving services so they must be safe for concurrent use and quick.
+type Observer interface {
+
+	// BeforeResolve is called before the registration is resolved. The Duration, Err, and
+	// CacheHit of the event are not yet known.
+	BeforeResolve(ResolveEvent)
+
+	// AfterResolve is called once the registration has been resolved, or failed to be.
+	AfterResolve(ResolveEvent)
+}
+
+// A ResolveEvent describes the resolution of a registration to an [Observer].
+type ResolveEvent struct {
+	Service  ServiceID
+	Lifetime ServiceLifetime
+	// ScopeID identifies the scope the service was resolved from. The top level ServiceProvider
+	// and every scope created from it have distinct ids.
+	ScopeID uint64
+	// Duration is how long resolving the service took, including resolving its dependencies and
+	// waiting for another goroutine that was already creating a shared instance.
+	Duration time.Duration
+	Err      error
+	// CacheHit is whether an existing [Singleton] or [Scoped] instance was shared instead of the
+	// factory being called.
+	CacheHit bool
+}
+
+// statsSamples is how many of the most recent durations a StatsCollector keeps per service to
+// calculate percentiles from.
+const statsSamples = 1024
+
+// A StatsCollector is an [Observer] which counts the resolutions of each service and measures how
+// long their factories take.
+//
+//	stats := inject.NewStatsCollector()
+//	provider, err := services.Build(inject.EagerSingletons(), inject.Observe(stats))
+//	for _, s := range stats.Stats() {
+//		log.Printf("%v: created %d times, p99 %v", s.Service, s.Created, s.P99)
+//	}
+type StatsCollector struct {
+	mu       sync.Mutex
+	services map[ServiceID]*serviceStats
+}
+
+type serviceStats struct {
+	ServiceStats
+	// durations is a ring of the durations of the most recent resolutions that created instances.
+	durations []time.Duration
+	next      int
+}
+
+// ServiceStats are the statistics a [StatsCollector] has collected for one service. The
+// percentiles are of the durations of the most recent 1024 resolutions that created an instance,
+// which include the time taken to resolve the dependencies of the instance.
+type ServiceStats struct {
+	Service  ServiceID
+	Lifetime ServiceLifetime
+	// Resolutions is how many times the service was resolved.
+	Resolutions int
+	// Created is how many times the factory of the service was called.
+	Created int
+	// CacheHits is how many times an existing instance of the service was shared.
+	CacheHits int
+	// Errors is how many times resolving the service failed.
+	Errors int
+	P50    time.Duration
+	P90    time.Duration
+	P99    time.Duration
+	Max    time.Duration
+}
+
+// NewStatsCollector creates an empty [StatsCollector].
+func NewStatsCollector() *StatsCollector {
+	return &StatsCollector{services: make(map[ServiceID]*serviceStats)}
+}
+
+// BeforeResolve does nothing; a StatsCollector only needs the outcome of each resolution.
+func (collector *StatsCollector) BeforeResolve(ResolveEvent) {}
+
+// AfterResolve records the resolution described by the event.
+func (collector *StatsCollector) AfterResolve(event ResolveEvent) {
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	stats, ok := collector.services[event.Service]
+	if !ok {
+		stats = &serviceStats{ServiceStats: ServiceStats{Service: event.Service}}
+		collector.services[event.Service] = stats
+	}
+	stats.Lifetime = event.Lifetime
+	stats.Resolutions++
+	switch {
+	case event.Err != nil:
+		stats.Errors++
+	case event.CacheHit:
+		stats.CacheHits++
+	default:
+		stats.Created++
+		if len(stats.durations) < statsSamples {
+			stats.durations = append(stats.durations, event.Duration)
+		} else {
+			stats.durations[stats.next] = event.Duration
+			stats.next = (stats.next + 1) % statsSamples
+		}
+		stats.Max = max(stats.Max, event.Duration)
+	}
+}
+
+// Stats returns the statistics collected so far for every service that has been resolved, ordered
+// by service.
+func (collector *StatsCollector) Stats() []ServiceStats {
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	all := make([]ServiceStats, 0, len(collector.services))
+	for _, stats := range collector.services {
+		result := stats.ServiceStats
+		sorted := slices.Clone(stats.durations)
+		slices.Sort(sorted)
+		result.P50 = percentile(sorted, 50)
+		result.P90 = percentile(sorted, 90)
+		result.P99 = percentile(sorted, 99)
+		all = append(all, result)
+	}
+	slices.SortFunc(all, func(a, b ServiceStats) int {
+		return strings.Compare(a.Service.String(), b.Service.String())
+	})
+	return all
+}
+
+// percentile returns the nearest rank percentile of the sorted durations.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank, 1)-1]
+}
@@ -0,0 +1,134 @@
+package inject
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+// recordingObserver records the events it's notified of as strings.
+type recordingObserver struct {
+	events []string
+	after  []ResolveEvent
+}
+
+func (o *recordingObserver) BeforeResolve(event ResolveEvent) {
+	o.events = append(o.events, fmt.Sprintf("before %v", event.Service))
+}
+
+func (o *recordingObserver) AfterResolve(event ResolveEvent) {
+	o.events = append(o.events, fmt.Sprintf("after %v", event.Service))
+	o.after = append(o.after, event)
+}
+
+func TestObserve(t *testing.T) {
+
+	t.Run("notifies observers around dependencies", func(t *testing.T) {
+		observer := &recordingObserver{}
+		services := ServiceCollection{}
+		RegisterType[fooer](&services, Singleton, &assignableToFooer{})
+		RegisterType(&services, Transient, &dependsOnFooer{})
+		provider, _ := services.Build(Observe(observer))
+
+		_, _ = Resolve[*dependsOnFooer](&provider)
+
+		expected := []string{
+			"before *inject.dependsOnFooer",
+			"before inject.fooer",
+			"after inject.fooer",
+			"after *inject.dependsOnFooer",
+		}
+		if !slices.Equal(observer.events, expected) {
+			t.Fatalf("expected %q; got %q", expected, observer.events)
+		}
+		if observer.after[0].Lifetime != Singleton || observer.after[1].Lifetime != Transient {
+			t.Fatalf("expected lifetimes Singleton and Transient; got %v and %v",
+				observer.after[0].Lifetime, observer.after[1].Lifetime)
+		}
+	})
+
+	t.Run("reports cache hits and scope ids", func(t *testing.T) {
+		observer := &recordingObserver{}
+		services := ServiceCollection{}
+		RegisterType(&services, Scoped, &assignableToFooer{})
+		provider, _ := services.Build(Observe(observer))
+		first, second := provider.NewScope(), provider.NewScope()
+
+		_, _ = Resolve[*assignableToFooer](&first)
+		_, _ = Resolve[*assignableToFooer](&first)
+		_, _ = Resolve[*assignableToFooer](&second)
+
+		hits := []bool{observer.after[0].CacheHit, observer.after[1].CacheHit, observer.after[2].CacheHit}
+		if !slices.Equal(hits, []bool{false, true, false}) {
+			t.Fatalf("expected cache hits [false true false]; got %v", hits)
+		}
+		if observer.after[0].ScopeID != observer.after[1].ScopeID || observer.after[0].ScopeID == observer.after[2].ScopeID {
+			t.Fatalf("expected scope ids to identify scopes; got %d, %d, %d",
+				observer.after[0].ScopeID, observer.after[1].ScopeID, observer.after[2].ScopeID)
+		}
+	})
+
+	t.Run("reports errors", func(t *testing.T) {
+		observer := &recordingObserver{}
+		expectedErr := errors.New("nope")
+		services := ServiceCollection{}
+		RegisterFunc[*assignableToFooer](&services, Transient, func(ServiceResolver) (*assignableToFooer, error) {
+			return nil, expectedErr
+		})
+		provider, _ := services.Build(Observe(observer))
+
+		_, _ = Resolve[*assignableToFooer](&provider)
+
+		if !errors.Is(observer.after[0].Err, expectedErr) {
+			t.Fatalf("expected %q; got %q", expectedErr, observer.after[0].Err)
+		}
+	})
+}
+
+func TestStatsCollector(t *testing.T) {
+
+	t.Run("counts resolutions per service", func(t *testing.T) {
+		stats := NewStatsCollector()
+		services := ServiceCollection{}
+		RegisterType[fooer](&services, Singleton, &assignableToFooer{})
+		RegisterType(&services, Transient, &dependsOnFooer{})
+		provider, _ := services.Build(Observe(stats))
+
+		for range 3 {
+			_, _ = Resolve[*dependsOnFooer](&provider)
+		}
+
+		expected := []ServiceStats{
+			{Service: ServiceID{Type: reflect.TypeFor[*dependsOnFooer]()}, Lifetime: Transient, Resolutions: 3, Created: 3},
+			{Service: ServiceID{Type: reflect.TypeFor[fooer]()}, Lifetime: Singleton, Resolutions: 3, Created: 1, CacheHits: 2},
+		}
+		actual := stats.Stats()
+		for i := range actual {
+			// The durations aren't predictable.
+			actual[i].P50, actual[i].P90, actual[i].P99, actual[i].Max = 0, 0, 0, 0
+		}
+		if !slices.Equal(actual, expected) {
+			t.Fatalf("expected %+v; got %+v", expected, actual)
+		}
+	})
+
+	t.Run("calculates percentiles of the durations of created instances", func(t *testing.T) {
+		stats := NewStatsCollector()
+		id := ServiceID{Type: reflect.TypeFor[*assignableToFooer]()}
+		for i := range 100 {
+			stats.AfterResolve(ResolveEvent{Service: id, Duration: time.Duration(100-i) * time.Millisecond})
+		}
+		stats.AfterResolve(ResolveEvent{Service: id, Duration: time.Hour, CacheHit: true})
+
+		actual := stats.Stats()[0]
+
+		if actual.P50 != 50*time.Millisecond || actual.P90 != 90*time.Millisecond ||
+			actual.P99 != 99*time.Millisecond || actual.Max != 100*time.Millisecond {
+			t.Fatalf("expected 50ms, 90ms, 99ms, and 100ms; got %v, %v, %v, and %v",
+				actual.P50, actual.P90, actual.P99, actual.Max)
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package inject
+
+import (
+	"errors"
+	"reflect"
+)
+
+// An Optional is a T that may not be registered. Services that can do without a T, e.g. a cache,
+// can take an Optional[T] constructor parameter or field, which the [ServiceProvider] provides
+// without it being registered: it holds an instance of T if T is registered and is empty
+// otherwise. Other errors resolving T are still returned.
+type Optional[T any] struct {
+	value T
+	ok    bool
+}
+
+// Get returns the instance of T and true if T is registered, or the zero value and false if not.
+func (optional Optional[T]) Get() (T, bool) {
+	return optional.value, optional.ok
+}
+
+func (Optional[T]) dependency() reflect.Type {
+	return reflect.TypeFor[T]()
+}
+
+func (Optional[T]) with(service any) any {
+	// A nil interface can't be asserted to T but it's a valid T, i.e. the zero value.
+	value, _ := service.(T)
+	return Optional[T]{value: value, ok: true}
+}
+
+// An optional is an [Optional] of any type.
+type optional interface {
+	// dependency is the type of the service the Optional holds.
+	dependency() reflect.Type
+	// with creates an Optional holding the given instance of the service.
+	with(service any) any
+}
+
+var optionalType = reflect.TypeFor[optional]()
+
+// getOptional returns an optional for the requested service if its type is an Optional.
+func getOptional(key serviceKey) (optional, bool) {
+	if key.key != "" || key.type_ == nil || !key.type_.Implements(optionalType) {
+		return nil, false
+	}
+	optional, ok := reflect.Zero(key.type_).Interface().(optional)
+	return optional, ok
+}
+
+// underlying returns the key of the service an Optional holds for Optional keys, and the key
+// itself otherwise.
+func underlying(key serviceKey) serviceKey {
+	if optional, ok := getOptional(key); ok {
+		return keyFor(optional.dependency())
+	}
+	return key
+}
+
+// ResolveOptional obtains an instance of the requested type from a [ServiceResolver] like
+// [Resolve], but when T is not registered it returns the zero value and false rather than an
+// error. Errors resolving a T that is registered, including its own dependencies not being
+// registered, are returned.
+func ResolveOptional[T any](resolver ServiceResolver) (T, bool, error) {
+	service, err := Resolve[T](resolver)
+	if err != nil {
+		var zero T
+		if isNotRegistered(err, keyFor(reflect.TypeFor[T]())) {
+			return zero, false, nil
+		}
+		return zero, false, err
+	}
+	return service, true, nil
+}
+
+// isNotRegistered reports whether the given error reports that the requested service itself, not
+// one of its dependencies, is not registered.
+func isNotRegistered(err error, key serviceKey) bool {
+	if !errors.Is(err, ErrNotRegistered) {
+		return false
+	}
+	var resolutionErr *ResolutionError
+	if !errors.As(err, &resolutionErr) || len(resolutionErr.Path) == 0 {
+		return true
+	}
+	return resolutionErr.Path[len(resolutionErr.Path)-1] == ServiceID{key.type_, key.key}
+}
+
+// RegisterDefault registers a factory like [RegisterFunc] which is only used when no other
+// implementation is registered for the service type Service, e.g. a no-op logger. Unlike
+// [TryRegisterFunc] the order doesn't matter: registering any other implementation of Service,
+// before or after, removes the default. Registering another default replaces the default.
+func RegisterDefault[Service any, Impl any](
+	services *ServiceCollection,
+	lifetime ServiceLifetime,
+	factory func(ServiceResolver) (Impl, error),
+) error {
+	return registerFunc[Service](services, lifetime, "", factory, defaultRegistration)
+}
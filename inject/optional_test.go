@@ -0,0 +1,220 @@
+package inject
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type optionalCache struct {
+	entries map[string]string
+}
+
+type withOptionalField struct {
+	Cache   *optionalCache              `inject:"optional"`
+	Fooer   fooer                       `inject:"optional"`
+	Service *structWithUnexportedFields `inject:""`
+}
+
+type withOptionalParam struct {
+	cache Optional[*optionalCache]
+}
+
+func newWithOptionalParam(cache Optional[*optionalCache]) *withOptionalParam {
+	return &withOptionalParam{cache: cache}
+}
+
+func TestResolveOptional(t *testing.T) {
+
+	t.Run("returns false when not registered", func(t *testing.T) {
+		services := ServiceCollection{}
+		provider, err := services.Build()
+		if err != nil {
+			t.Fatalf("unexpected error from ServiceCollection.Build: %q", err)
+		}
+		cache, ok, err := ResolveOptional[*optionalCache](&provider)
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if ok || cache != nil {
+			t.Fatalf("expected no instance; got %v %v", cache, ok)
+		}
+	})
+
+	t.Run("returns instance when registered", func(t *testing.T) {
+		services := ServiceCollection{}
+		RegisterType(&services, Singleton, &optionalCache{})
+		provider, err := services.Build()
+		if err != nil {
+			t.Fatalf("unexpected error from ServiceCollection.Build: %q", err)
+		}
+		cache, ok, err := ResolveOptional[*optionalCache](&provider)
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if !ok || cache == nil {
+			t.Fatalf("expected an instance; got %v %v", cache, ok)
+		}
+	})
+
+	t.Run("returns error when a dependency is not registered", func(t *testing.T) {
+		resolver := mockResolver{}
+		resolver.returns(nil, &ResolutionError{
+			Path: []ServiceID{{Type: reflect.TypeFor[*withOptionalField]()}, {Type: reflect.TypeFor[*structWithUnexportedFields]()}},
+			Err:  ErrNotRegistered,
+		})
+		if _, _, err := ResolveOptional[*withOptionalField](&resolver); !errors.Is(err, ErrNotRegistered) {
+			t.Fatalf("expected %q; got %q", ErrNotRegistered, err)
+		}
+	})
+}
+
+func TestOptionalDependencies(t *testing.T) {
+
+	t.Run("fields tagged optional are left zero when not registered", func(t *testing.T) {
+		services := ServiceCollection{}
+		RegisterType(&services, Transient, &structWithUnexportedFields{})
+		RegisterType(&services, Transient, &withOptionalField{})
+		provider, err := services.Build()
+		if err != nil {
+			t.Fatalf("unexpected error from ServiceCollection.Build: %q", err)
+		}
+		resolved, err := Resolve[*withOptionalField](&provider)
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if resolved.Cache != nil || resolved.Fooer != nil || resolved.Service == nil {
+			t.Fatalf("expected only Service to be injected; got %+v", resolved)
+		}
+	})
+
+	t.Run("fields tagged optional are injected when registered", func(t *testing.T) {
+		services := ServiceCollection{}
+		RegisterType(&services, Transient, &structWithUnexportedFields{})
+		RegisterType(&services, Transient, &optionalCache{})
+		RegisterType(&services, Transient, &withOptionalField{})
+		provider, err := services.Build()
+		if err != nil {
+			t.Fatalf("unexpected error from ServiceCollection.Build: %q", err)
+		}
+		resolved, err := Resolve[*withOptionalField](&provider)
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if resolved.Cache == nil {
+			t.Fatal("expected Cache to be injected")
+		}
+	})
+
+	t.Run("Build still requires untagged fields", func(t *testing.T) {
+		services := ServiceCollection{}
+		RegisterType(&services, Transient, &withOptionalField{})
+		if _, err := services.Build(); !errors.Is(err, ErrMissingDependency) {
+			t.Fatalf("expected %q; got %q", ErrMissingDependency, err)
+		}
+	})
+
+	t.Run("Optional constructor parameters", func(t *testing.T) {
+		for _, registered := range []bool{false, true} {
+			services := ServiceCollection{}
+			if registered {
+				RegisterType(&services, Singleton, &optionalCache{})
+			}
+			RegisterConstructor[*withOptionalParam](&services, Transient, newWithOptionalParam)
+			provider, err := services.Build(ValidateOnBuild())
+			if err != nil {
+				t.Fatalf("unexpected error from ServiceCollection.Build: %q", err)
+			}
+			resolved, err := Resolve[*withOptionalParam](&provider)
+			if err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+			if cache, ok := resolved.cache.Get(); ok != registered || (cache != nil) != registered {
+				t.Fatalf("expected Get to return an instance %v; got %v %v", registered, cache, ok)
+			}
+		}
+	})
+
+	t.Run("Optional dependencies are part of cycles", func(t *testing.T) {
+		services := ServiceCollection{}
+		RegisterConstructor[*withOptionalParam](&services, Transient, newWithOptionalParam)
+		RegisterConstructor[*optionalCache](&services, Transient, func(*withOptionalParam) *optionalCache {
+			return &optionalCache{}
+		})
+		if _, err := services.Build(); !errors.Is(err, ErrCircularDependency) {
+			t.Fatalf("expected %q; got %q", ErrCircularDependency, err)
+		}
+	})
+}
+
+func TestRegisterDefault(t *testing.T) {
+	newDefault := func(ServiceResolver) (*optionalCache, error) {
+		return &optionalCache{}, nil
+	}
+	registered := &optionalCache{}
+	newRegistered := func(ServiceResolver) (*optionalCache, error) {
+		return registered, nil
+	}
+
+	testCases := []struct {
+		name     string
+		register func(*ServiceCollection)
+	}{
+		{
+			name: "registered before default",
+			register: func(services *ServiceCollection) {
+				RegisterFunc[*optionalCache](services, Singleton, newRegistered)
+				RegisterDefault[*optionalCache](services, Singleton, newDefault)
+			},
+		},
+		{
+			name: "registered after default",
+			register: func(services *ServiceCollection) {
+				RegisterDefault[*optionalCache](services, Singleton, newDefault)
+				RegisterFunc[*optionalCache](services, Singleton, newRegistered)
+			},
+		},
+		{
+			name: "try registered after default",
+			register: func(services *ServiceCollection) {
+				RegisterDefault[*optionalCache](services, Singleton, newDefault)
+				TryRegisterFunc[*optionalCache](services, Singleton, newRegistered)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			services := &ServiceCollection{}
+			tc.register(services)
+			provider, err := services.Build()
+			if err != nil {
+				t.Fatalf("unexpected error from ServiceCollection.Build: %q", err)
+			}
+			all, err := ResolveAll[*optionalCache](&provider)
+			if err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+			if len(all) != 1 || all[0] != registered {
+				t.Fatalf("expected only the registered instance; got %v", all)
+			}
+		})
+	}
+
+	t.Run("used when nothing else is registered", func(t *testing.T) {
+		services := ServiceCollection{}
+		RegisterDefault[*optionalCache](&services, Singleton, newDefault)
+		RegisterDefault[*optionalCache](&services, Singleton, newRegistered)
+		provider, err := services.Build()
+		if err != nil {
+			t.Fatalf("unexpected error from ServiceCollection.Build: %q", err)
+		}
+		all, err := ResolveAll[*optionalCache](&provider)
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if len(all) != 1 || all[0] != registered {
+			t.Fatalf("expected only the last default; got %v", all)
+		}
+	})
+}
@@ -0,0 +1,282 @@
+package inject
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+)
+
+// Options holds a configuration value of type T which is built from the configuration sources
+// registered with [ConfigureFromEnv], [ConfigureFromJSONFile], [ConfigureFromFlags], and
+// [ConfigureFunc]. Constructors and injected fields of type Options[T] are given the fully
+// populated value without registering anything else.
+//
+// The value is built once per top level [ServiceProvider], the first time it's resolved. Fields
+// with a `default` struct tag start with that value. The configuration sources are then applied in
+// the order they were registered, each overriding the fields set by those before it. Finally,
+// fields with a `validate:"required"` struct tag must have non-zero values and, if T or *T has a
+// Validate() error method, it must return nil.
+//
+//	type DBConfig struct {
+//		Host    string        `default:"localhost" validate:"required"`
+//		Port    int           `default:"5432"`
+//		Timeout time.Duration `env:"DIAL_TIMEOUT" default:"5s"`
+//	}
+type Options[T any] struct {
+	value *T
+}
+
+// Value returns a shallow copy of the configuration value. Setting the fields of the copy does not
+// affect the value given to other services, but the copy shares any slices, maps, and pointers it
+// holds with that value, so their contents must not be changed.
+func (options Options[T]) Value() T {
+	if options.value == nil {
+		var zero T
+		return zero
+	}
+	return *options.value
+}
+
+// A configuration holds the sources configured for an Options[T] in the order they were added.
+type configuration[T any] struct {
+	sources []func(*T) error
+}
+
+// ConfigureFunc adds a function which sets fields of the value given to [Options][T].
+func ConfigureFunc[T any](services *ServiceCollection, configure func(*T) error) error {
+	if configure == nil {
+		return errors.New("cannot configure options with nil func")
+	}
+	return addConfiguration(services, configure)
+}
+
+// ConfigureFromEnv adds environment variables as a source of the value given to [Options][T]. Each
+// field is read from the variable named by the prefix followed by the field's `env` struct tag or,
+// without a tag, the field name in upper snake case. Fields of nested structs are read using the
+// nested field name followed by an underscore as the prefix. For example, with the prefix "APP_",
+// the field Timeout is read from APP_TIMEOUT and the field DB.MaxConns is read from
+// APP_DB_MAX_CONNS. Variables that aren't set leave their fields unchanged.
+func ConfigureFromEnv[T any](services *ServiceCollection, prefix string) error {
+	if err := requireStruct[T](); err != nil {
+		return err
+	}
+	return addConfiguration(services, func(value *T) error {
+		return walkFields(reflect.ValueOf(value).Elem(), func(field reflect.Value, path fieldPath) error {
+			raw, ok := os.LookupEnv(prefix + path.envName())
+			if !ok {
+				return nil
+			}
+			if err := setField(field, raw); err != nil {
+				return fmt.Errorf("%s%s: %w", prefix, path.envName(), err)
+			}
+			return nil
+		})
+	})
+}
+
+// ConfigureFromJSONFile adds the JSON file at the given path as a source of the value given to
+// [Options][T]. The file is read when the value is built and decoded over the value as it is at
+// that point, so fields missing from the file are left unchanged.
+func ConfigureFromJSONFile[T any](services *ServiceCollection, path string) error {
+	return addConfiguration(services, func(value *T) error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, value); err != nil {
+			return fmt.Errorf("decoding %s: %w", path, err)
+		}
+		return nil
+	})
+}
+
+// ConfigureFromFlags defines a flag in the given [flag.FlagSet] for each field of T and adds the
+// flags that are set when the value is built as a source of the value given to [Options][T]. Each
+// flag is named by the prefix followed by the field's `flag` struct tag or, without a tag, the
+// field name in lower kebab case, and uses the field's `usage` struct tag as its usage. Fields of
+// nested structs use the nested field name followed by a dash as the prefix. Since the flags are
+// defined immediately, ConfigureFromFlags must be called before the FlagSet is parsed.
+func ConfigureFromFlags[T any](services *ServiceCollection, flags *flag.FlagSet, prefix string) error {
+	if flags == nil {
+		return errors.New("cannot configure options from nil FlagSet")
+	}
+	if err := requireStruct[T](); err != nil {
+		return err
+	}
+	// Flags are applied in the order they're set so the last of any repeated flags wins.
+	var set []func(*T) error
+	var zero T
+	err := walkFields(reflect.ValueOf(&zero).Elem(), func(_ reflect.Value, path fieldPath) error {
+		name := prefix + path.flagName()
+		flags.Func(name, path.usage(), func(raw string) error {
+			if err := setField(reflect.New(path.type_()).Elem(), raw); err != nil {
+				return err
+			}
+			set = append(set, func(value *T) error {
+				return setField(path.lookup(reflect.ValueOf(value).Elem()), raw)
+			})
+			return nil
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return addConfiguration(services, func(value *T) error {
+		for _, apply := range set {
+			if err := apply(value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// addConfiguration adds a source to the configuration for T, registering the services that
+// provide Options[T] the first time T is configured.
+func addConfiguration[T any](services *ServiceCollection, source func(*T) error) error {
+	if services == nil {
+		return errors.New("cannot configure options for a nil ServiceCollection")
+	}
+	var added *configuration[T]
+	err := services.update(func(target *ServiceCollection) error {
+		if existing, ok := target.configurations[reflect.TypeFor[T]()]; ok {
+			config := existing.(*configuration[T])
+			config.sources = append(config.sources, source)
+			return nil
+		}
+		added = &configuration[T]{sources: []func(*T) error{source}}
+		target.setConfiguration(reflect.TypeFor[T](), added)
+		return nil
+	})
+	if err != nil || added == nil {
+		return err
+	}
+	return added.register(services, appendRegistration)
+}
+
+// An anyConfiguration is a *configuration[T] for any T.
+type anyConfiguration interface {
+
+	// copy copies the configuration so that configuring the copy doesn't affect the original.
+	copy() anyConfiguration
+
+	// replace adds the configuration to the given ServiceCollection, replacing any configuration
+	// of the same type and the services that provide its Options[T].
+	replace(*ServiceCollection) error
+
+	// sourceCount is the number of sources the configuration has.
+	sourceCount() int
+
+	// truncate removes the sources added after the first n.
+	truncate(n int)
+}
+
+func (config *configuration[T]) copy() anyConfiguration {
+	return &configuration[T]{sources: slices.Clone(config.sources)}
+}
+
+func (config *configuration[T]) sourceCount() int {
+	return len(config.sources)
+}
+
+func (config *configuration[T]) truncate(n int) {
+	config.sources = config.sources[:n:n]
+}
+
+func (config *configuration[T]) replace(services *ServiceCollection) error {
+	err := services.update(func(target *ServiceCollection) error {
+		target.setConfiguration(reflect.TypeFor[T](), config)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return config.register(services, replaceRegistrations)
+}
+
+func (services *ServiceCollection) setConfiguration(type_ reflect.Type, config anyConfiguration) {
+	if services.configurations == nil {
+		services.configurations = make(map[reflect.Type]anyConfiguration)
+	}
+	services.configurations[type_] = config
+}
+
+// register registers the service that provides Options[T] from the configuration.
+func (config *configuration[T]) register(services *ServiceCollection, mode registrationMode) error {
+	// Options[T] is a struct, which can't be registered as a Singleton through the public API, but
+	// copies of it share the built value so sharing it is safe. Registering it directly avoids a
+	// separate Singleton for the value that users could see in the Graph and resolve themselves.
+	return services.addRegistration(keyFor(reflect.TypeFor[Options[T]]()), serviceRegistration{
+		lifetime: Singleton,
+		implType: reflect.TypeFor[Options[T]](),
+		factory: func(ServiceResolver) (any, error) {
+			built, err := config.build()
+			if err != nil {
+				return nil, err
+			}
+			return Options[T]{built}, nil
+		},
+	}, mode)
+}
+
+// build creates the value for the configuration by applying defaults, then sources, then
+// validation.
+func (config *configuration[T]) build() (*T, error) {
+	built := new(T)
+	value := reflect.ValueOf(built).Elem()
+	if value.Kind() == reflect.Struct {
+		err := walkFields(value, func(field reflect.Value, path fieldPath) error {
+			if raw, ok := path.tag("default"); ok {
+				if err := setField(field, raw); err != nil {
+					return fmt.Errorf("default for %s: %w", path, err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, source := range config.sources {
+		if err := source(built); err != nil {
+			return nil, err
+		}
+	}
+	if err := validateOptions(value); err != nil {
+		return nil, fmt.Errorf("invalid %v: %w", value.Type(), err)
+	}
+	return built, nil
+}
+
+// validateOptions checks the fields tagged as required and calls the Validate method of the value
+// if it has one.
+func validateOptions(value reflect.Value) error {
+	var errs []error
+	if value.Kind() == reflect.Struct {
+		err := walkFields(value, func(field reflect.Value, path fieldPath) error {
+			if rule, ok := path.tag("validate"); ok && rule == "required" && field.IsZero() {
+				errs = append(errs, fmt.Errorf("%s is required", path))
+			}
+			return nil
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if validator, ok := value.Addr().Interface().(interface{ Validate() error }); ok {
+		errs = append(errs, validator.Validate())
+	}
+	return errors.Join(errs...)
+}
+
+func requireStruct[T any]() error {
+	if type_ := reflect.TypeFor[T](); type_.Kind() != reflect.Struct {
+		return fmt.Errorf("options type must be a struct; got %v", type_)
+	}
+	return nil
+}
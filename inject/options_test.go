@@ -0,0 +1,200 @@
+package inject
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testDBConfig struct {
+	Host     string        `default:"localhost" validate:"required"`
+	Port     int           `default:"5432"`
+	Timeout  time.Duration `env:"DIAL_TIMEOUT" default:"5s"`
+	MaxConns uint
+	Replicas []string
+	Pool     struct {
+		Size int `default:"4"`
+	}
+}
+
+type validatedConfig struct {
+	Valid bool
+}
+
+func (config validatedConfig) Validate() error {
+	if !config.Valid {
+		return errors.New("not valid")
+	}
+	return nil
+}
+
+type dependsOnOptions struct {
+	Options Options[testDBConfig] `inject:""`
+}
+
+func TestOptions(t *testing.T) {
+
+	resolve := func(t *testing.T, services *ServiceCollection) (testDBConfig, error) {
+		t.Helper()
+		provider, err := services.Build()
+		if err != nil {
+			t.Fatalf("unexpected error from ServiceCollection.Build: %q", err)
+		}
+		options, err := Resolve[Options[testDBConfig]](&provider)
+		return options.Value(), err
+	}
+
+	t.Run("defaults are applied from struct tags", func(t *testing.T) {
+		services := ServiceCollection{}
+		ConfigureFunc(&services, func(*testDBConfig) error { return nil })
+		config, err := resolve(t, &services)
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if config.Host != "localhost" || config.Port != 5432 || config.Timeout != 5*time.Second || config.Pool.Size != 4 {
+			t.Fatalf("expected defaults; got %+v", config)
+		}
+	})
+
+	t.Run("sources are layered in order", func(t *testing.T) {
+		t.Setenv("APP_HOST", "env-host")
+		t.Setenv("APP_PORT", "1")
+		t.Setenv("APP_DIAL_TIMEOUT", "1m")
+		t.Setenv("APP_MAX_CONNS", "7")
+		t.Setenv("APP_REPLICAS", "a, b")
+		t.Setenv("APP_POOL_SIZE", "8")
+		path := filepath.Join(t.TempDir(), "config.json")
+		os.WriteFile(path, []byte(`{"Port": 2}`), 0o600)
+
+		services := ServiceCollection{}
+		ConfigureFromEnv[testDBConfig](&services, "APP_")
+		ConfigureFromJSONFile[testDBConfig](&services, path)
+		ConfigureFunc(&services, func(config *testDBConfig) error {
+			config.Host += "+func"
+			return nil
+		})
+		config, err := resolve(t, &services)
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if config.Host != "env-host+func" || config.Port != 2 || config.Timeout != time.Minute ||
+			config.MaxConns != 7 || !reflect.DeepEqual(config.Replicas, []string{"a", "b"}) || config.Pool.Size != 8 {
+			t.Fatalf("unexpected value %+v", config)
+		}
+	})
+
+	t.Run("flags that are set are applied", func(t *testing.T) {
+		flags := flag.NewFlagSet("test", flag.ContinueOnError)
+		services := ServiceCollection{}
+		ConfigureFromFlags[testDBConfig](&services, flags, "db-")
+		if err := flags.Parse([]string{"-db-max-conns", "3", "-db-pool-size=9"}); err != nil {
+			t.Fatalf("unexpected error from FlagSet.Parse: %q", err)
+		}
+		config, err := resolve(t, &services)
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if config.MaxConns != 3 || config.Pool.Size != 9 || config.Port != 5432 {
+			t.Fatalf("unexpected value %+v", config)
+		}
+	})
+
+	t.Run("invalid flag values are rejected when parsed", func(t *testing.T) {
+		flags := flag.NewFlagSet("test", flag.ContinueOnError)
+		flags.SetOutput(io.Discard)
+		services := ServiceCollection{}
+		ConfigureFromFlags[testDBConfig](&services, flags, "")
+		if err := flags.Parse([]string{"-port", "nope"}); err == nil {
+			t.Fatal("expected error; got <nil>")
+		}
+	})
+
+	t.Run("required fields are validated", func(t *testing.T) {
+		services := ServiceCollection{}
+		ConfigureFunc(&services, func(config *testDBConfig) error {
+			config.Host = ""
+			return nil
+		})
+		if _, err := resolve(t, &services); err == nil {
+			t.Fatal("expected error; got <nil>")
+		}
+	})
+
+	t.Run("Validate method is called", func(t *testing.T) {
+		services := ServiceCollection{}
+		ConfigureFunc(&services, func(*validatedConfig) error { return nil })
+		provider, _ := services.Build()
+		if _, err := Resolve[Options[validatedConfig]](&provider); err == nil {
+			t.Fatal("expected error; got <nil>")
+		}
+	})
+
+	t.Run("source errors are returned", func(t *testing.T) {
+		expectedErr := errors.New("expected error")
+		services := ServiceCollection{}
+		ConfigureFunc(&services, func(*testDBConfig) error { return expectedErr })
+		if _, err := resolve(t, &services); !errors.Is(err, expectedErr) {
+			t.Fatalf("expected %q; got %q", expectedErr, err)
+		}
+	})
+
+	t.Run("options are injected and values are copies", func(t *testing.T) {
+		services := ServiceCollection{}
+		ConfigureFunc(&services, func(*testDBConfig) error { return nil })
+		RegisterType(&services, Transient, &dependsOnOptions{})
+		provider, err := services.Build()
+		if err != nil {
+			t.Fatalf("unexpected error from ServiceCollection.Build: %q", err)
+		}
+		resolved, err := Resolve[*dependsOnOptions](&provider)
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		value := resolved.Options.Value()
+		value.Host = "changed"
+		if host := resolved.Options.Value().Host; host != "localhost" {
+			t.Fatalf("expected %q; got %q", "localhost", host)
+		}
+	})
+
+	t.Run("only Options is visible in the graph", func(t *testing.T) {
+		services := ServiceCollection{}
+		ConfigureFunc(&services, func(*testDBConfig) error { return nil })
+		expected := []GraphService{{
+			Service:        "inject.Options[github.com/ttd2089/stahp/inject.testDBConfig]",
+			Implementation: "inject.Options[github.com/ttd2089/stahp/inject.testDBConfig]",
+			Lifetime:       Singleton,
+			Type:           "github.com/ttd2089/stahp/inject.Options[github.com/ttd2089/stahp/inject.testDBConfig]",
+		}}
+		if actual := services.Graph().Services; !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("expected %+v; got %+v", expected, actual)
+		}
+	})
+
+	t.Run("the value is built once per top level provider", func(t *testing.T) {
+		services := ServiceCollection{}
+		builds := 0
+		ConfigureFunc(&services, func(*testDBConfig) error {
+			builds++
+			return nil
+		})
+		provider, err := services.Build()
+		if err != nil {
+			t.Fatalf("unexpected error from ServiceCollection.Build: %q", err)
+		}
+		scope := provider.NewScope()
+		for _, resolver := range []ServiceResolver{&provider, &scope, &provider} {
+			if _, err := Resolve[Options[testDBConfig]](resolver); err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+		}
+		if builds != 1 {
+			t.Fatalf("expected 1 build; got %d", builds)
+		}
+	})
+}
@@ -0,0 +1,178 @@
+package inject
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// A fieldPath is the chain of struct fields leading from an options value to one of its fields.
+type fieldPath []reflect.StructField
+
+func (path fieldPath) String() string {
+	names := make([]string, len(path))
+	for i, field := range path {
+		names[i] = field.Name
+	}
+	return strings.Join(names, ".")
+}
+
+// envName is the name of the environment variable for the field, without the prefix.
+func (path fieldPath) envName() string {
+	names := make([]string, len(path))
+	for i, field := range path {
+		if name, ok := field.Tag.Lookup("env"); ok {
+			names[i] = name
+		} else {
+			names[i] = strings.ToUpper(splitWords(field.Name, '_'))
+		}
+	}
+	return strings.Join(names, "_")
+}
+
+// flagName is the name of the flag for the field, without the prefix.
+func (path fieldPath) flagName() string {
+	names := make([]string, len(path))
+	for i, field := range path {
+		if name, ok := field.Tag.Lookup("flag"); ok {
+			names[i] = name
+		} else {
+			names[i] = strings.ToLower(splitWords(field.Name, '-'))
+		}
+	}
+	return strings.Join(names, "-")
+}
+
+func (path fieldPath) usage() string {
+	usage, _ := path.tag("usage")
+	return usage
+}
+
+func (path fieldPath) tag(name string) (string, bool) {
+	return path[len(path)-1].Tag.Lookup(name)
+}
+
+func (path fieldPath) type_() reflect.Type {
+	return path[len(path)-1].Type
+}
+
+// lookup finds the field in the given struct value.
+func (path fieldPath) lookup(v reflect.Value) reflect.Value {
+	for _, field := range path {
+		v = v.FieldByIndex(field.Index)
+	}
+	return v
+}
+
+// splitWords separates the words in a Go identifier with the given separator, e.g. MaxConns
+// becomes Max_Conns and DBHost becomes DB_Host.
+func splitWords(name string, separator rune) string {
+	runes := []rune(name)
+	var sb strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if !unicode.IsUpper(prev) || nextIsLower {
+				sb.WriteRune(separator)
+			}
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+var textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+// walkFields calls fn for every exported field of the given struct value, descending into nested
+// structs unless they can decode themselves from text, e.g. time.Time.
+func walkFields(v reflect.Value, fn func(reflect.Value, fieldPath) error) error {
+	return walkFieldsFrom(v, nil, fn)
+}
+
+func walkFieldsFrom(v reflect.Value, parent fieldPath, fn func(reflect.Value, fieldPath) error) error {
+	type_ := v.Type()
+	for i := range type_.NumField() {
+		field := type_.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		path := append(parent[:len(parent):len(parent)], field)
+		fieldValue := v.Field(i)
+		if field.Type.Kind() == reflect.Struct && !reflect.PointerTo(field.Type).Implements(textUnmarshalerType) {
+			if err := walkFieldsFrom(fieldValue, path, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(fieldValue, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var durationType = reflect.TypeFor[time.Duration]()
+
+// setField parses raw according to the type of the given addressable value and sets it. Slices are
+// parsed from comma separated lists.
+func setField(v reflect.Value, raw string) error {
+	if unmarshaler, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return unmarshaler.UnmarshalText([]byte(raw))
+	}
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(raw, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(raw, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		var parts []string
+		if raw != "" {
+			parts = strings.Split(raw, ",")
+		}
+		slice := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := setField(slice.Index(i), strings.TrimSpace(part)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	default:
+		return fmt.Errorf("cannot set field of type %v from text", v.Type())
+	}
+	return nil
+}
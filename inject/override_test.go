@@ -0,0 +1,128 @@
+package inject
+
+import (
+	"context"
+	"testing"
+)
+
+func TestOverride(t *testing.T) {
+
+	newServices := func() *ServiceCollection {
+		services := &ServiceCollection{}
+		RegisterFunc[namedString](services, Singleton, func(ServiceResolver) (namedString, error) {
+			return "production", nil
+		})
+		RegisterConstructor[*namingFooer](services, Transient, func(name namedString) *namingFooer {
+			return &namingFooer{name: string(name)}
+		})
+		ConfigureFunc(services, func(config *testDBConfig) error {
+			config.Host = "production"
+			return nil
+		})
+		return services
+	}
+
+	override := func(services *ServiceCollection) error {
+		if err := ReplaceFunc[namedString](services, Singleton, func(ServiceResolver) (namedString, error) {
+			return "test", nil
+		}); err != nil {
+			return err
+		}
+		return ConfigureFunc(services, func(config *testDBConfig) error {
+			config.Host = "test"
+			return nil
+		})
+	}
+
+	assertResolves := func(t *testing.T, provider *ServiceProvider, expected string) {
+		t.Helper()
+		fooer, err := Resolve[*namingFooer](provider)
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if fooer.name != expected {
+			t.Fatalf("expected %q; got %q", expected, fooer.name)
+		}
+		options, err := Resolve[Options[testDBConfig]](provider)
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if options.Value().Host != expected {
+			t.Fatalf("expected %q; got %q", expected, options.Value().Host)
+		}
+	}
+
+	t.Run("ServiceCollection.Override does not change the ServiceCollection", func(t *testing.T) {
+		services := newServices()
+
+		overridden, err := services.Override(override)
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		original, _ := services.Build()
+
+		assertResolves(t, &overridden, "test")
+		assertResolves(t, &original, "production")
+	})
+
+	t.Run("ServiceProvider.Override does not share instances with the ServiceProvider", func(t *testing.T) {
+		services := newServices()
+		log := &disposalLog{}
+		RegisterFunc[*closer](services, Singleton, func(ServiceResolver) (*closer, error) {
+			return &closer{name: "singleton", log: log}, nil
+		})
+		original, _ := services.Build()
+		originalCloser := MustResolve[*closer](&original)
+
+		overridden, err := original.Override(override)
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+
+		assertResolves(t, &overridden, "test")
+		assertResolves(t, &original, "production")
+		if MustResolve[*closer](&overridden) == originalCloser {
+			t.Fatalf("expected distinct Singleton instances")
+		}
+		_ = overridden.Close(context.Background())
+		if _, err := Resolve[*closer](&original); err != nil {
+			t.Fatalf("closing the overridden ServiceProvider closed the original: %q", err)
+		}
+	})
+
+	t.Run("ServiceProvider.Override keeps the BuildOptions", func(t *testing.T) {
+		services := &ServiceCollection{}
+		RegisterType(services, Scoped, &assignableToFooer{})
+		provider, _ := services.Build(StrictLifetimes())
+
+		overridden, err := provider.Override(func(*ServiceCollection) error { return nil })
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+
+		if _, err := Resolve[*assignableToFooer](&overridden); err == nil {
+			t.Fatalf("expected error; got nil")
+		}
+	})
+}
+
+func TestOnDispose(t *testing.T) {
+
+	log := &disposalLog{}
+	services := ServiceCollection{}
+	RegisterFunc[*closer](&services, Scoped, func(ServiceResolver) (*closer, error) {
+		return &closer{name: "scoped", log: log}, nil
+	})
+	var disposed []any
+	provider, _ := services.Build(OnDispose(func(service any, err error) {
+		disposed = append(disposed, service)
+	}))
+	scope := provider.NewScope()
+	service := MustResolve[*closer](&scope)
+
+	_ = scope.Close(context.Background())
+
+	if len(disposed) != 1 || disposed[0] != service {
+		t.Fatalf("expected [%p]; got %v", service, disposed)
+	}
+}
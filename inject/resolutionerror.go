@@ -0,0 +1,80 @@
+package inject
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// A ServiceID identifies a service by its type and, for services registered with a key, its key.
+type ServiceID struct {
+	Type reflect.Type
+	Key  string
+}
+
+func (id ServiceID) String() string {
+	return serviceKey{id.Type, id.Key}.String()
+}
+
+// A ResolutionError is returned by a [ServiceProvider] when a service can't be resolved. It
+// records the chain of services that were being resolved so the failure can be traced from the
+// service that was requested to the service that failed, e.g.
+//
+//	*main.Handler -> main.UserRepo -> *sql.DB: dial tcp: connection refused
+//
+// The underlying cause can be inspected with [errors.Is] and [errors.As] as usual.
+type ResolutionError struct {
+	// Path is the chain of services being resolved, starting with the service that was requested
+	// and ending with the service that couldn't be resolved.
+	Path []ServiceID
+	// Err is the cause of the failure, e.g. an error returned by a factory, [ErrNotRegistered],
+	// or [ErrCircularDependency].
+	Err error
+}
+
+func (err *ResolutionError) Error() string {
+	var sb strings.Builder
+	for i, id := range err.Path {
+		if i > 0 {
+			sb.WriteString(" -> ")
+		}
+		sb.WriteString(id.String())
+	}
+	return fmt.Sprintf("%s: %v", sb.String(), err.Err)
+}
+
+func (err *ResolutionError) Unwrap() error {
+	return err.Err
+}
+
+// newResolutionError attributes err to the given path. Errors which already carry a path are
+// attributed to the given path followed by theirs. Their path continues from the last service in
+// the given path when it includes it, e.g. for errors from deeper in the same resolution or from
+// another resolution of a shared instance, and from the start otherwise, e.g. for errors from a
+// [Lazy] whose resolutions start afresh.
+func newResolutionError(path []serviceKey, err error) error {
+	ids := make([]ServiceID, len(path))
+	for i, key := range path {
+		ids[i] = ServiceID{key.type_, key.key}
+	}
+	resolutionErr := (*ResolutionError)(nil)
+	if !errors.As(err, &resolutionErr) {
+		return &ResolutionError{Path: ids, Err: err}
+	}
+	nested := resolutionErr.Path
+	if len(ids) > 0 {
+		if i := slices.Index(nested, ids[len(ids)-1]); i >= 0 {
+			nested = nested[i+1:]
+		}
+	}
+	combined := append(ids, nested...)
+	if slices.Equal(combined, resolutionErr.Path) {
+		return err
+	}
+	if err == error(resolutionErr) {
+		return &ResolutionError{Path: combined, Err: resolutionErr.Err}
+	}
+	return &ResolutionError{Path: combined, Err: err}
+}
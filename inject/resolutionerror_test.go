@@ -0,0 +1,91 @@
+package inject
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestResolutionError(t *testing.T) {
+
+	newServices := func(factoryErr error) *ServiceCollection {
+		services := &ServiceCollection{}
+		RegisterType(services, Transient, &dependsOnDependsOnFooer{})
+		RegisterType(services, Transient, &dependsOnFooer{})
+		RegisterFunc[fooer](services, Transient, func(ServiceResolver) (*assignableToFooer, error) {
+			return nil, factoryErr
+		})
+		return services
+	}
+
+	t.Run("records the full resolution path", func(t *testing.T) {
+		services := newServices(errors.New("dial failed"))
+		provider, _ := services.Build()
+		_, err := Resolve[*dependsOnDependsOnFooer](&provider)
+		var resolutionErr *ResolutionError
+		if !errors.As(err, &resolutionErr) {
+			t.Fatalf("expected *ResolutionError; got %T", err)
+		}
+		expectedPath := []ServiceID{
+			{Type: reflect.TypeFor[*dependsOnDependsOnFooer]()},
+			{Type: reflect.TypeFor[*dependsOnFooer]()},
+			{Type: reflect.TypeFor[fooer]()},
+		}
+		if !reflect.DeepEqual(resolutionErr.Path, expectedPath) {
+			t.Fatalf("expected %v; got %v", expectedPath, resolutionErr.Path)
+		}
+		expected := "*inject.dependsOnDependsOnFooer -> *inject.dependsOnFooer -> inject.fooer: dial failed"
+		if actual := err.Error(); actual != expected {
+			t.Fatalf("expected %q; got %q", expected, actual)
+		}
+	})
+
+	t.Run("wraps the underlying cause", func(t *testing.T) {
+		expectedErr := errors.New("expected error")
+		services := newServices(expectedErr)
+		provider, _ := services.Build()
+		_, err := Resolve[*dependsOnDependsOnFooer](&provider)
+		if !errors.Is(err, expectedErr) {
+			t.Fatalf("expected %q; got %q", expectedErr, err)
+		}
+	})
+
+	t.Run("records the path to unregistered services", func(t *testing.T) {
+		services := ServiceCollection{}
+		RegisterFunc[*dependsOnFooer](&services, Transient, func(r ServiceResolver) (*dependsOnFooer, error) {
+			_, err := Resolve[fooer](r)
+			return nil, err
+		})
+		provider, _ := services.Build()
+		_, err := Resolve[*dependsOnFooer](&provider)
+		if !errors.Is(err, ErrNotRegistered) {
+			t.Fatalf("expected %q; got %q", ErrNotRegistered, err)
+		}
+		expected := "*inject.dependsOnFooer -> inject.fooer: no implementation registered"
+		if actual := err.Error(); actual != expected {
+			t.Fatalf("expected %q; got %q", expected, actual)
+		}
+	})
+
+	t.Run("records the path through Lazy dependencies", func(t *testing.T) {
+		services := newServices(errors.New("dial failed"))
+		RegisterConstructor[*structWithLazyFields](services, Transient, func(lazy Lazy[*dependsOnFooer]) (*structWithLazyFields, error) {
+			_, err := lazy.Get()
+			return &structWithLazyFields{}, err
+		})
+		provider, _ := services.Build()
+		_, err := Resolve[*structWithLazyFields](&provider)
+		var resolutionErr *ResolutionError
+		if !errors.As(err, &resolutionErr) {
+			t.Fatalf("expected *ResolutionError; got %T", err)
+		}
+		expectedPath := []ServiceID{
+			{Type: reflect.TypeFor[*structWithLazyFields]()},
+			{Type: reflect.TypeFor[*dependsOnFooer]()},
+			{Type: reflect.TypeFor[fooer]()},
+		}
+		if !reflect.DeepEqual(resolutionErr.Path, expectedPath) {
+			t.Fatalf("expected %v; got %v", expectedPath, resolutionErr.Path)
+		}
+	})
+}
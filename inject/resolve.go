@@ -1,6 +1,7 @@
 package inject
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
@@ -14,6 +15,42 @@ type ServiceResolver interface {
 	Resolve(reflect.Type) (any, error)
 }
 
+// A KeyedServiceResolver is a [ServiceResolver] which can also resolve instances of services that
+// were registered under a key, e.g. with [RegisterKeyed].
+type KeyedServiceResolver interface {
+	ServiceResolver
+
+	// ResolveKeyed provides an instance of the requested type registered under the given key if
+	// one is registered. Implementations MUST ensure that the values returned are assignable to
+	// the requested type.
+	ResolveKeyed(reflect.Type, string) (any, error)
+}
+
+// A MultiServiceResolver is a [ServiceResolver] which can also resolve every implementation
+// registered for a service type.
+type MultiServiceResolver interface {
+	ServiceResolver
+
+	// ResolveAll provides an instance of every implementation registered for the requested type,
+	// in the order they were registered. Implementations MUST ensure that the values returned are
+	// assignable to the requested type.
+	ResolveAll(reflect.Type) ([]any, error)
+}
+
+// A ContextServiceResolver is a [ServiceResolver] which resolves services with a context, e.g. a
+// startup deadline or the context of a request, that is given to the factories of the services
+// and bounds how long resolving them may take.
+type ContextServiceResolver interface {
+	ServiceResolver
+
+	// Context returns the context services are resolved with.
+	Context() context.Context
+
+	// WithContext returns a ServiceResolver which resolves the same services as the target but
+	// with the given context.
+	WithContext(context.Context) ServiceResolver
+}
+
 // Resolve obtains an instance of the requested type from a [ServiceResolver]. An error is returned
 // when the [ServiceResolver] returns an error and when the value returned by the [ServiceResolver]
 // is not assignable to T.
@@ -34,6 +71,28 @@ func Resolve[T any](resolver ServiceResolver) (T, error) {
 	return typed, nil
 }
 
+// ResolveContext obtains an instance of the requested type from a [ServiceResolver] like [Resolve]
+// but with the given context. When the [ServiceResolver] is a [ContextServiceResolver] the context
+// is given to every factory involved in creating the instance, including the factories of its
+// dependencies, and an error is returned when the context is done before the instance is created.
+// The path of the service whose factory was running when the deadline passed is included in the
+// [ResolutionError]. [Singleton] factories receive the context without its cancellation or
+// deadline since their instances outlive the resolution. Other resolvers only have the context
+// checked before resolving.
+func ResolveContext[T any](ctx context.Context, resolver ServiceResolver) (T, error) {
+	var zero T
+	if resolver == nil {
+		return zero, errors.New("cannot resolve instances from nil ServiceResolver")
+	}
+	if contextual, ok := resolver.(ContextServiceResolver); ok {
+		return Resolve[T](contextual.WithContext(ctx))
+	}
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+	return Resolve[T](resolver)
+}
+
 // MustResolve obtains an intance of the requested type from a [ServiceResolver] and panics when
 // the [ServiceResolver] returns an error and when the value returned by the [ServiceResolver] is
 // not assignable to T.
@@ -44,3 +103,79 @@ func MustResolve[T any](resolver ServiceResolver) T {
 	}
 	return service
 }
+
+// ResolveKeyed obtains an instance of the requested type registered under the given key from a
+// [ServiceResolver]. An error is returned when the [ServiceResolver] is not a
+// [KeyedServiceResolver], when it returns an error, and when the value it returns is not
+// assignable to T. Resolving with the empty key is the same as calling [Resolve].
+func ResolveKeyed[T any](resolver ServiceResolver, key string) (T, error) {
+	var zero T
+	if resolver == nil {
+		return zero, errors.New("cannot resolve instances from nil ServiceResolver")
+	}
+	type_ := reflect.TypeFor[T]()
+	resolved, err := resolveKey(resolver, serviceKey{type_, key})
+	if err != nil {
+		return zero, err
+	}
+	typed, ok := resolved.(T)
+	if !ok {
+		return typed, fmt.Errorf("ServiceResolver returned %T when %T was requested", resolved, zero)
+	}
+	return typed, nil
+}
+
+// MustResolveKeyed obtains an instance of the requested type registered under the given key like
+// [ResolveKeyed] and panics in every case where [ResolveKeyed] would return an error.
+func MustResolveKeyed[T any](resolver ServiceResolver, key string) T {
+	service, err := ResolveKeyed[T](resolver, key)
+	if err != nil {
+		panic(err)
+	}
+	return service
+}
+
+// ResolveAll obtains an instance of every implementation registered for the requested type from a
+// [ServiceResolver], e.g. every HealthCheck registered by the different parts of an application.
+// An error is returned when the [ServiceResolver] is not a [MultiServiceResolver], when it returns
+// an error, and when any value it returns is not assignable to T.
+func ResolveAll[T any](resolver ServiceResolver) ([]T, error) {
+	if resolver == nil {
+		return nil, errors.New("cannot resolve instances from nil ServiceResolver")
+	}
+	multi, ok := resolver.(MultiServiceResolver)
+	if !ok {
+		return nil, fmt.Errorf("cannot resolve all %v from %T which does not implement MultiServiceResolver", reflect.TypeFor[T](), resolver)
+	}
+	resolved, err := multi.ResolveAll(reflect.TypeFor[T]())
+	if err != nil {
+		return nil, err
+	}
+	typed := make([]T, len(resolved))
+	for i, service := range resolved {
+		var ok bool
+		if typed[i], ok = service.(T); !ok {
+			return nil, fmt.Errorf("ServiceResolver returned %T when %v was requested", service, reflect.TypeFor[T]())
+		}
+	}
+	return typed, nil
+}
+
+func resolveKey(resolver ServiceResolver, key serviceKey) (any, error) {
+	if key.key == "" {
+		return resolver.Resolve(key.type_)
+	}
+	keyed, ok := resolver.(KeyedServiceResolver)
+	if !ok {
+		return nil, fmt.Errorf("cannot resolve %v from %T which does not implement KeyedServiceResolver", key, resolver)
+	}
+	return keyed.ResolveKeyed(key.type_, key.key)
+}
+
+// contextOf returns the context the given resolver resolves services with.
+func contextOf(resolver ServiceResolver) context.Context {
+	if contextual, ok := resolver.(ContextServiceResolver); ok {
+		return contextual.Context()
+	}
+	return context.Background()
+}
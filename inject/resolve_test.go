@@ -52,12 +52,54 @@ func TestResolve(t *testing.T) {
 	})
 }
 
+func TestResolveKeyed(t *testing.T) {
+
+	t.Run("requests the key from the KeyedServiceResolver", func(t *testing.T) {
+		resolver := mockResolver{}
+		resolver.returns(0, nil)
+		_, _ = ResolveKeyed[int](&resolver, "replica")
+		if resolver.requestedKeys[0] != "replica" {
+			t.Fatalf("expected %q; got %q", "replica", resolver.requestedKeys[0])
+		}
+	})
+
+	t.Run("returns error when the ServiceResolver does not support keys", func(t *testing.T) {
+		resolver := unkeyedResolver{}
+		if _, err := ResolveKeyed[int](&resolver, "replica"); err == nil {
+			t.Fatal("expected error; got <nil>")
+		}
+	})
+
+	t.Run("resolves without key from any ServiceResolver", func(t *testing.T) {
+		resolver := unkeyedResolver{}
+		resolver.returns(1, nil)
+		actual, err := ResolveKeyed[int](&resolver, "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if actual != 1 {
+			t.Fatalf("expected %v; got %v", 1, actual)
+		}
+	})
+}
+
+func TestResolveAll(t *testing.T) {
+
+	t.Run("returns error when the ServiceResolver does not support resolving all", func(t *testing.T) {
+		resolver := mockResolver{}
+		if _, err := ResolveAll[int](&resolver); err == nil {
+			t.Fatal("expected error; got <nil>")
+		}
+	})
+}
+
 type mockResolver struct {
 	returnValues []struct {
 		v   any
 		err error
 	}
 	requestedTypes []reflect.Type
+	requestedKeys  []string
 }
 
 func (mock *mockResolver) returns(v any, err error) {
@@ -79,3 +121,21 @@ func (mock *mockResolver) Resolve(type_ reflect.Type) (any, error) {
 	mock.returnValues = mock.returnValues[1:]
 	return r.v, r.err
 }
+
+func (mock *mockResolver) ResolveKeyed(type_ reflect.Type, key string) (any, error) {
+	mock.requestedKeys = append(mock.requestedKeys, key)
+	return mock.Resolve(type_)
+}
+
+// unkeyedResolver is a mockResolver which only implements ServiceResolver.
+type unkeyedResolver struct {
+	mock mockResolver
+}
+
+func (r *unkeyedResolver) returns(v any, err error) {
+	r.mock.returns(v, err)
+}
+
+func (r *unkeyedResolver) Resolve(type_ reflect.Type) (any, error) {
+	return r.mock.Resolve(type_)
+}
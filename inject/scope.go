@@ -0,0 +1,254 @@
+package inject
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrProviderClosed is returned when resolving services from a [ServiceProvider] that has been
+// closed, or whose Singleton instances have been closed by closing the top level provider.
+var ErrProviderClosed = errors.New("ServiceProvider is closed")
+
+// A Disposer is a service which holds resources that must be released when the [ServiceProvider]
+// that created it is closed. Services that only implement [io.Closer] are also released but
+// Disposer is preferred when a service implements both.
+type Disposer interface {
+	Dispose(context.Context) error
+}
+
+// errFactoryPanicked is given to anyone waiting on an instance whose factory panicked. The panic
+// itself continues up the stack of the goroutine that called the factory.
+var errFactoryPanicked = errors.New("factory panicked while constructing instance")
+
+// A scope holds the instances of services that are shared within it and coordinates their
+// construction so that each instance is built at most once, even when multiple goroutines resolve
+// the same service concurrently.
+type scope struct {
+	// id identifies the scope to observers.
+	id        uint64
+	mu        sync.Mutex
+	instances map[*serviceRegistration]*instance
+	// disposables are the instances created in the scope that need to be released when it is
+	// closed, in the order they were created.
+	disposables []any
+	// shared are the Singleton instances of a root scope, which other registrations may return but
+	// must not track again. Instances that can't be compared aren't recorded.
+	shared map[any]struct{}
+	closed bool
+	// onDispose are called with every instance the scope disposes.
+	onDispose []func(any, error)
+}
+
+// scopeIDs generates the ids of scopes.
+var scopeIDs atomic.Uint64
+
+func newScope(options *buildOptions) *scope {
+	s := &scope{id: scopeIDs.Add(1)}
+	if options != nil {
+		s.onDispose = options.onDispose
+	}
+	return s
+}
+
+// An instance is a service that has been, or is being, constructed in a scope. The done channel
+// is closed once the factory has returned and service, err, and cancelled are safe to read.
+type instance struct {
+	done    chan struct{}
+	service any
+	err     error
+	// cancelled is whether the instance failed because the context of the resolution that was
+	// creating it is done, which says nothing about the resolutions waiting for it.
+	cancelled bool
+}
+
+// creating is a link in the chain of instances being created by a resolution, which is carried by
+// the context given to their factories, and by the Lazy and Factory values bound by them.
+type creating struct {
+	instance *instance
+	parent   *creating
+}
+
+type creatingKey struct{}
+
+// isCreating reports whether the given instance is being created by the resolution with ctx, in
+// which case waiting for it would never end.
+func isCreating(ctx context.Context, existing *instance) bool {
+	for link, _ := ctx.Value(creatingKey{}).(*creating); link != nil; link = link.parent {
+		if link.instance == existing {
+			select {
+			case <-existing.done:
+				return false
+			default:
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getOrCreate returns the instance for the given registration in the scope, calling create to
+// construct and track it if no instance exists yet. The lock is only held while looking up the instance,
+// never while calling create, so that create may itself resolve other services from the same
+// scope. Waiting for an instance that another goroutine is creating stops when ctx is done, and
+// when the other goroutine fails because its own context is done the instance is created again.
+// Create is given a context that records the instance being created so that resolving it again
+// before it's finished, e.g. through a Lazy its factory was given, fails with
+// [ErrCircularDependency] rather than waiting for itself.
+func (s *scope) getOrCreate(
+	ctx context.Context,
+	registration *serviceRegistration,
+	create func(context.Context) (any, error),
+) (any, error) {
+	s.mu.Lock()
+	for {
+		if s.closed {
+			s.mu.Unlock()
+			return nil, ErrProviderClosed
+		}
+		existing, ok := s.instances[registration]
+		if !ok {
+			break
+		}
+		s.mu.Unlock()
+		if isCreating(ctx, existing) {
+			return nil, ErrCircularDependency
+		}
+		select {
+		case <-existing.done:
+			if !existing.cancelled {
+				return existing.service, existing.err
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		s.mu.Lock()
+	}
+	// We would have initialized this but since we can't stop someone from creating a default
+	// instance we need to avoid writes to nil maps.
+	if s.instances == nil {
+		s.instances = make(map[*serviceRegistration]*instance)
+	}
+	created := &instance{done: make(chan struct{})}
+	s.instances[registration] = created
+	s.mu.Unlock()
+
+	finished := false
+	defer func() {
+		if !finished {
+			created.err = errFactoryPanicked
+		}
+		// Failures are not saved so that a later resolution can try again.
+		if created.err != nil {
+			s.mu.Lock()
+			delete(s.instances, registration)
+			s.mu.Unlock()
+		}
+		close(created.done)
+	}()
+	parent, _ := ctx.Value(creatingKey{}).(*creating)
+	created.service, created.err = create(context.WithValue(ctx, creatingKey{}, &creating{created, parent}))
+	finished = true
+	if err := ctx.Err(); err != nil && errors.Is(created.err, err) {
+		created.cancelled = true
+	}
+	return created.service, created.err
+}
+
+// track remembers the given service for disposal if it needs it. If the scope was closed while
+// the service was being created then it is disposed immediately and [ErrProviderClosed] is
+// returned.
+func (s *scope) track(service any) error {
+	if !isDisposable(service) {
+		return nil
+	}
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return errors.Join(ErrProviderClosed, s.dispose(context.Background(), service))
+	}
+	s.disposables = append(s.disposables, service)
+	s.mu.Unlock()
+	return nil
+}
+
+// share records a Singleton instance so that registrations which return it, e.g. funcs that
+// resolve it, leave it to the registration it belongs to.
+func (s *scope) share(service any) {
+	if !isDisposable(service) || !reflect.TypeOf(service).Comparable() {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.shared == nil {
+		s.shared = make(map[any]struct{})
+	}
+	s.shared[service] = struct{}{}
+}
+
+// isShared reports whether the given instance was recorded by share.
+func (s *scope) isShared(service any) bool {
+	if !isDisposable(service) || !reflect.TypeOf(service).Comparable() {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.shared[service]
+	return ok
+}
+
+// close disposes every instance tracked by the scope in the reverse of the order they were
+// created so that services are released before the services they depend on.
+func (s *scope) close(ctx context.Context) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	disposables := s.disposables
+	s.disposables = nil
+	s.mu.Unlock()
+
+	var errs []error
+	for i := len(disposables) - 1; i >= 0; i-- {
+		errs = append(errs, s.dispose(ctx, disposables[i]))
+	}
+	return errors.Join(errs...)
+}
+
+func (s *scope) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// dispose disposes the given service and notifies the onDispose hooks.
+func (s *scope) dispose(ctx context.Context, service any) error {
+	err := dispose(ctx, service)
+	for _, onDispose := range s.onDispose {
+		onDispose(service, err)
+	}
+	return err
+}
+
+func isDisposable(service any) bool {
+	switch service.(type) {
+	case Disposer, io.Closer:
+		return true
+	}
+	return false
+}
+
+func dispose(ctx context.Context, service any) error {
+	switch d := service.(type) {
+	case Disposer:
+		return d.Dispose(ctx)
+	case io.Closer:
+		return d.Close()
+	}
+	return nil
+}
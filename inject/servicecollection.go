@@ -1,10 +1,12 @@
 package inject
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"maps"
 	"reflect"
+	"slices"
 	"sync"
 )
 
@@ -18,151 +20,558 @@ var ErrNonTransientStruct = errors.New("struct types may only be registered as T
 // it cannot be assigned to.
 var ErrInvalidImplementation = errors.New("implementation type must be assignable to service type")
 
+// ErrCollectionFrozen is returned when registering services to a [ServiceCollection] that has been
+// built or frozen with [ServiceCollection.Freeze].
+var ErrCollectionFrozen = errors.New("ServiceCollection is frozen")
+
+// ErrUnsupportedType is returned when a type is registered with [RegisterType] that the
+// [ServiceProvider] does not know how to construct, e.g. func and interface types.
+var ErrUnsupportedType = errors.New("type cannot be constructed by the ServiceProvider")
+
 // A ServiceCollection is a collection into which services can be registered and from which a
-// [ServiceProvider] may be built.
+// [ServiceProvider] may be built. Services may be registered from multiple goroutines
+// concurrently. Once the ServiceCollection is built, or frozen with [ServiceCollection.Freeze],
+// every attempt to change it returns [ErrCollectionFrozen]. A ServiceCollection must not be copied
+// after first use; use [ServiceCollection.Clone] instead.
 type ServiceCollection struct {
-	registrations map[reflect.Type]serviceRegistration
+	mu     sync.Mutex
+	frozen bool
+	// parent is the ServiceCollection that a Module's view registers services to.
+	parent *ServiceCollection
+	// module is the name of the Module a view registers services for.
+	module string
+	// installation is the installation of Modules a view makes changes for, if any.
+	installation *installation
+	// changes counts the changes made to the ServiceCollection.
+	changes uint64
+	// registrations holds every registration for each service in the order they were made. The
+	// last registration for a service is the one used to resolve it.
+	registrations map[serviceKey][]*serviceRegistration
+	// decorators holds the decorators for each service in the order they were registered.
+	decorators map[serviceKey][]decoratorFunc
+	// configurations holds the *configuration[T] for each T configured for Options[T].
+	configurations map[reflect.Type]anyConfiguration
+	// modules holds the installed modules by name.
+	modules map[string]*Module
 }
 
 // Build creates a [ServiceProvider] from the target [ServiceCollection]. A non-nil error is
 // returned when the [ServiceCollection] is determined to be in a bad state at the time of the
 // call, e.g. if a registered service has a dependency on a service type for which no
-// implementation is registered, or if there are circular dependencies.
-func (services *ServiceCollection) Build() (ServiceProvider, error) {
+// implementation is registered, if there are circular dependencies, or if a [Singleton] service
+// depends on a [Scoped] service, directly or through [Transient] services. Only the dependencies
+// of services registered with [RegisterType] and [RegisterConstructor] are known to Build. The
+// [ServiceProvider] can be configured with [BuildOption] values. Build freezes the
+// ServiceCollection, even when it returns an error, so that the ServiceProvider always reflects
+// the registrations; [ServiceCollection.Clone] it to make more.
+func (services *ServiceCollection) Build(opts ...BuildOption) (ServiceProvider, error) {
 	if services == nil {
 		return ServiceProvider{}, errors.New("cannot build ServiceProvider from nil ServiceCollection")
 	}
-	// TODO: analyze graph for validity
-	registrations := make(map[reflect.Type]serviceRegistration, len(services.registrations))
-	maps.Copy(registrations, services.registrations)
-	return ServiceProvider{
-		registrations:   registrations,
-		scopedInstances: make(map[reflect.Type]any, len(services.registrations)),
-	}, nil
+	options := &buildOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return services.build(options)
 }
 
-func (services *ServiceCollection) addRegistration(serviceType reflect.Type, registration serviceRegistration) {
-	if services.registrations == nil {
-		services.registrations = make(map[reflect.Type]serviceRegistration)
+// Freeze prevents any further changes to the ServiceCollection. Registering services to it, or
+// changing it in any other way, returns [ErrCollectionFrozen] afterwards.
+func (services *ServiceCollection) Freeze() {
+	if services == nil {
+		return
 	}
-	services.registrations[serviceType] = registration
+	target := services.target()
+	target.mu.Lock()
+	defer target.mu.Unlock()
+	target.frozen = true
 }
 
-// A ServiceProvider is a factory from which services can be resolved by type.
-type ServiceProvider struct {
-	registrations   map[reflect.Type]serviceRegistration
-	mu              sync.Mutex
-	scopedInstances map[reflect.Type]any
+// target returns the ServiceCollection that holds the registrations, which is the parent for the
+// view of a Module.
+func (services *ServiceCollection) target() *ServiceCollection {
+	if services.parent != nil {
+		return services.parent
+	}
+	return services
 }
 
-// NewScope creates a new ServiceProvider which will create distinct instances when resolving any
-// [Scoped] services.
-func (provider *ServiceProvider) NewScope() ServiceProvider {
-	panic("unimplemented")
+// update calls change with the ServiceCollection that holds the registrations while holding its
+// lock, unless it's frozen.
+func (services *ServiceCollection) update(change func(*ServiceCollection) error) error {
+	target := services.target()
+	target.mu.Lock()
+	defer target.mu.Unlock()
+	if target.frozen {
+		return ErrCollectionFrozen
+	}
+	target.changes++
+	if services.installation != nil {
+		services.installation.changes++
+	}
+	return change(target)
 }
 
-// Resolve provides an instance of the requested type if one is registered.
-func (provider *ServiceProvider) Resolve(type_ reflect.Type) (any, error) {
-	if provider == nil {
-		return nil, errors.New("cannot resolve instances from nil ServiceProvider")
+// Clone creates a copy of the target [ServiceCollection] which can be changed without affecting the
+// original, and vice versa.
+// Instances registered with [RegisterInstance] and [Owned] are shared with the clone but are only
+// disposed by the providers built from the original.
+func (services *ServiceCollection) Clone() *ServiceCollection {
+	if services == nil {
+		return nil
+	}
+	services = services.target()
+	services.mu.Lock()
+	clone := &ServiceCollection{}
+	if services.registrations != nil {
+		clone.registrations = make(map[serviceKey][]*serviceRegistration, len(services.registrations))
+		borrowed := make(map[*serviceRegistration]*serviceRegistration)
+		for key, registered := range services.registrations {
+			clone.registrations[key] = borrowInstances(registered, borrowed)
+		}
+	}
+	if services.decorators != nil {
+		clone.decorators = make(map[serviceKey][]decoratorFunc, len(services.decorators))
+		for key, decorators := range services.decorators {
+			clone.decorators[key] = slices.Clone(decorators)
+		}
+	}
+	if services.modules != nil {
+		clone.modules = maps.Clone(services.modules)
 	}
-	registration, ok := provider.registrations[type_]
-	if !ok {
-		return nil, fmt.Errorf("no implementation registered for service type %v", type_)
+	configurations := make([]anyConfiguration, 0, len(services.configurations))
+	for _, config := range services.configurations {
+		configurations = append(configurations, config.copy())
 	}
-	switch registration.lifetime {
-	case Transient:
-		return registration.factory(provider)
-	case Scoped:
-		return provider.resolveScoped(type_, registration.factory)
-	case Singleton:
-		return registration.factory(provider)
-	default:
-		panic("this code should be unreachable: please open a an issue at https://github.com/ttd2089/stahp/issues/new")
+	services.mu.Unlock()
+	for _, config := range configurations {
+		// The clone isn't frozen yet so this can't fail.
+		_ = config.replace(clone)
 	}
+	return clone
 }
 
-func (provider *ServiceProvider) resolveScoped(type_ reflect.Type, factory factoryFunc) (any, error) {
-	// No need to lock if we've already saved the scoped instance.
-	if service, ok := provider.scopedInstances[type_]; ok {
-		return service, nil
+// Override builds a [ServiceProvider] like [ServiceCollection.Build] from a clone of the target
+// [ServiceCollection] after making the registrations in override to it, e.g. replacing the mailer
+// and clock of an application with fakes in an integration test. The target ServiceCollection is
+// not changed.
+//
+//	provider, err := services.Override(func(services *inject.ServiceCollection) error {
+//		return inject.ReplaceFunc[Clock](services, inject.Singleton, newFakeClock)
+//	})
+func (services *ServiceCollection) Override(
+	override func(*ServiceCollection) error,
+	opts ...BuildOption,
+) (ServiceProvider, error) {
+	if services == nil {
+		return ServiceProvider{}, errors.New("cannot build ServiceProvider from nil ServiceCollection")
 	}
-	provider.mu.Lock()
-	defer provider.mu.Unlock()
-	// Someone may have saved a scoped instance while we were waiting for a lock so check again.
-	if service, ok := provider.scopedInstances[type_]; ok {
-		return service, nil
+	clone := services.Clone()
+	if err := override(clone); err != nil {
+		return ServiceProvider{}, err
 	}
-	// Build, save, and return the scoped instance.
-	service, err := factory(provider)
-	if err != nil {
-		return nil, err
+	return clone.Build(opts...)
+}
+
+func (services *ServiceCollection) build(options *buildOptions) (ServiceProvider, error) {
+	services.Freeze()
+	// Nothing can change a frozen ServiceCollection so it can be read without the lock.
+	services = services.target()
+	if err := errors.Join(validateModules(services), validate(services.registrations)); err != nil {
+		return ServiceProvider{}, err
+	}
+	registrations := make(map[serviceKey][]*serviceRegistration, len(services.registrations))
+	for key, registered := range services.registrations {
+		registrations[key] = decorate(registered, services.decorators[key])
+	}
+	if options.validateOnBuild {
+		if err := validateByResolving(registrations, options); err != nil {
+			return ServiceProvider{}, err
+		}
 	}
-	// We would have initialized this but since we can't stop someone from creating a default
-	// instance we need to avoid writes to nil maps.
-	if provider.scopedInstances == nil {
-		provider.scopedInstances = make(map[reflect.Type]any, len(provider.registrations))
+	provider := newServiceProvider(registrations, options)
+	provider.source = services
+	if options.eagerSingletons {
+		if err := provider.createSingletons(options.parallelEager); err != nil {
+			return ServiceProvider{}, errors.Join(err, provider.Close(context.Background()))
+		}
+	}
+	return provider, nil
+}
+
+// A registrationMode determines how a new registration is combined with any existing
+// registrations for the same service.
+type registrationMode int
+
+const (
+	// appendRegistration adds the registration after any existing registrations.
+	appendRegistration registrationMode = iota
+	// tryAddRegistration adds the registration only if there are no existing registrations.
+	tryAddRegistration
+	// replaceRegistrations removes any existing registrations before adding the registration.
+	replaceRegistrations
+	// defaultRegistration adds the registration as the default, which is only kept until any
+	// other registration is added.
+	defaultRegistration
+)
+
+func (services *ServiceCollection) addRegistration(key serviceKey, registration serviceRegistration, mode registrationMode) error {
+	return services.addRegistrations([]serviceKey{key}, &registration, mode)
+}
+
+// addRegistrations adds the same registration for each of the given services so that they share
+// its instances, which are cached by registration.
+func (services *ServiceCollection) addRegistrations(keys []serviceKey, registration *serviceRegistration, mode registrationMode) error {
+	registration.module = services.module
+	registration.isDefault = mode == defaultRegistration
+	return services.update(func(target *ServiceCollection) error {
+		if target.registrations == nil {
+			target.registrations = make(map[serviceKey][]*serviceRegistration)
+		}
+		for _, key := range keys {
+			// A default is only ever registered on its own so the existing registrations are
+			// either a default or none are.
+			existing := target.registrations[key]
+			hasDefault := len(existing) > 0 && existing[0].isDefault
+			switch mode {
+			case tryAddRegistration, defaultRegistration:
+				if len(existing) > 0 && !hasDefault {
+					continue
+				}
+				delete(target.registrations, key)
+			case replaceRegistrations:
+				delete(target.registrations, key)
+			default:
+				if hasDefault {
+					delete(target.registrations, key)
+				}
+			}
+			target.registrations[key] = append(target.registrations[key], registration)
+		}
+		return nil
+	})
+}
+
+// A serviceKey identifies a registration by its service type and, for keyed registrations, its
+// key. The empty key identifies the registration that isn't keyed.
+type serviceKey struct {
+	type_ reflect.Type
+	key   string
+}
+
+func keyFor(type_ reflect.Type) serviceKey {
+	return serviceKey{type_: type_}
+}
+
+func (key serviceKey) String() string {
+	if key.key == "" {
+		return key.type_.String()
 	}
-	provider.scopedInstances[type_] = service
-	return service, nil
+	return fmt.Sprintf("%v[key=%s]", key.type_, key.key)
 }
 
 type factoryFunc func(ServiceResolver) (any, error)
 
 type serviceRegistration struct {
 	lifetime ServiceLifetime
+	// implType is the type of the instances created by the factory.
+	implType reflect.Type
 	factory  factoryFunc
+	// dependencies are the services the factory is known to resolve. Registrations built from
+	// arbitrary funcs may resolve services that aren't listed.
+	dependencies []dependency
+	// module is the name of the Module that made the registration, if any.
+	module string
+	// isDefault is whether the registration was made with RegisterDefault.
+	isDefault bool
+	// instance is whether the registration was made with RegisterInstance, in which case the
+	// instance is only disposed if it's owned.
+	instance bool
+	owned    bool
+	// alias is whether the factory returns the instance of another registration, which disposes
+	// it if anything does.
+	alias bool
+	// decorated is the registration this registration applies decorators to, if any.
+	decorated  *serviceRegistration
+	decorators []decoratorFunc
+}
+
+// A dependency is a service resolved by the factory of another service.
+type dependency struct {
+	serviceKey
+	// field is the name of the struct field the dependency is injected into, if any.
+	field string
+	// optional is whether the dependency may be missing, i.e. for `inject:"optional"` fields.
+	optional bool
 }
 
 // RegisterType registers the type of the given T as the concrete type to satisfy the service type
 // T when instances are resolved from a [ServiceProvider] built from the given [ServiceCollection].
-// After the instance is resolved, every exported field will be initialized by the same
-// [ServiceProvider]. Note that the given instance of T is not used directly even for types
-// registered with Singleton lifetime.
+// After the instance is resolved, every exported field with an `inject` struct tag will be
+// initialized by the same [ServiceProvider]; fields without the tag are left as they are. Note
+// that the given instance of T is not used directly even for types registered with Singleton
+// lifetime.
+//
+// The tag contains a comma separated list of options: `inject:""` resolves the field by its type,
+// `inject:"key=replica"` resolves the field using the registration made with [RegisterKeyed] for
+// the key "replica", `inject:"optional"` leaves the field uninitialized if its type is not
+// registered, and `inject:"-"` leaves the field uninitialized.
+//
+// Structs, arrays, and primitive types are resolved as their zero values, maps and slices as new
+// empty instances, and pointers as pointers to new values resolved the same way, e.g. a *int as a
+// pointer to a new 0. Channels are resolved as new channels with the same buffer size as the given
+// instance of T, so to register a buffered channel pass e.g. make(chan int, 10). Types that cannot
+// be constructed, such as funcs and pointers to them, cause [ErrUnsupportedType] to be returned.
 func RegisterType[T any](services *ServiceCollection, lifetime ServiceLifetime, type_ T) error {
+	return registerType(services, lifetime, type_, appendRegistration)
+}
+
+// TryRegisterType registers the type of the given T like [RegisterType] but only if no
+// implementation is already registered for the service type T.
+func TryRegisterType[T any](services *ServiceCollection, lifetime ServiceLifetime, type_ T) error {
+	return registerType(services, lifetime, type_, tryAddRegistration)
+}
+
+// ReplaceType registers the type of the given T like [RegisterType] after removing every
+// implementation already registered for the service type T.
+func ReplaceType[T any](services *ServiceCollection, lifetime ServiceLifetime, type_ T) error {
+	return registerType(services, lifetime, type_, replaceRegistrations)
+}
+
+func registerType[T any](services *ServiceCollection, lifetime ServiceLifetime, type_ T, mode registrationMode) error {
 	if services == nil {
 		return errors.New("cannot register types to a nil ServiceProvider")
 	}
 
 	implType := reflect.TypeOf(type_)
+	if implType == nil {
+		return fmt.Errorf("%w: cannot determine the type of a nil interface value", ErrUnsupportedType)
+	}
 
 	if lifetime != Transient && implType.Kind() == reflect.Struct {
 		return ErrNonTransientStruct
 	}
 
-	factory, err := getDefaultFactory(implType)
+	factory, dependencies, err := getDefaultFactory(implType)
 	if err != nil {
 		return err
 	}
+	if implType.Kind() == reflect.Chan {
+		// The buffer size of the given channel is used as the buffer size of the instances.
+		factory = getChanFactory(implType, reflect.ValueOf(type_).Cap())
+	}
 
-	services.addRegistration(reflect.TypeFor[T](), serviceRegistration{
-		lifetime: lifetime,
-		factory:  factory,
-	})
-
-	return nil
+	return services.addRegistration(keyFor(reflect.TypeFor[T]()), serviceRegistration{
+		lifetime:     lifetime,
+		implType:     implType,
+		factory:      factory,
+		dependencies: dependencies,
+	}, mode)
 }
 
-func getDefaultFactory(type_ reflect.Type) (factoryFunc, error) {
+func getDefaultFactory(type_ reflect.Type) (factoryFunc, []dependency, error) {
 	// How we initialize the impl depends on the kind.
-	if type_.Kind() == reflect.Struct {
+	switch type_.Kind() {
+	case reflect.Struct:
+		fields, err := getInjectedFields(type_)
+		if err != nil {
+			return nil, nil, err
+		}
+		return func(resolver ServiceResolver) (any, error) {
+			v := reflect.New(type_).Elem()
+			if err := injectFields(resolver, v, fields); err != nil {
+				return nil, err
+			}
+			return v.Interface(), nil
+		}, fieldDependencies(fields), nil
+	case reflect.Array,
+		reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
 		return func(ServiceResolver) (any, error) {
 			return reflect.Zero(type_).Interface(), nil
-		}, nil
-	}
-	if type_.Kind() == reflect.Pointer && type_.Elem().Kind() == reflect.Struct {
+		}, nil, nil
+	case reflect.Pointer:
 		elemType := type_.Elem()
+		if elemType.Kind() == reflect.Struct {
+			fields, err := getInjectedFields(elemType)
+			if err != nil {
+				return nil, nil, err
+			}
+			return func(resolver ServiceResolver) (any, error) {
+				v := reflect.New(elemType)
+				if err := injectFields(resolver, v.Elem(), fields); err != nil {
+					return nil, err
+				}
+				return v.Interface(), nil
+			}, fieldDependencies(fields), nil
+		}
+		elemFactory, dependencies, err := getDefaultFactory(elemType)
+		if errors.Is(err, ErrUnsupportedType) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		return func(resolver ServiceResolver) (any, error) {
+			elem, err := elemFactory(resolver)
+			if err != nil {
+				return nil, err
+			}
+			v := reflect.New(elemType)
+			v.Elem().Set(reflect.ValueOf(elem))
+			return v.Interface(), nil
+		}, dependencies, nil
+	case reflect.Map:
+		return func(ServiceResolver) (any, error) {
+			return reflect.MakeMap(type_).Interface(), nil
+		}, nil, nil
+	case reflect.Slice:
 		return func(ServiceResolver) (any, error) {
-			return reflect.New(elemType).Interface(), nil
-		}, nil
+			return reflect.MakeSlice(type_, 0, 0).Interface(), nil
+		}, nil, nil
+	case reflect.Chan:
+		// Directional channels can't be created by reflection (or at all really).
+		if type_.ChanDir() == reflect.BothDir {
+			return getChanFactory(type_, 0), nil, nil
+		}
 	}
-	panic("unimplemented")
+	return nil, nil, fmt.Errorf("%w: %v", ErrUnsupportedType, type_)
 }
 
+func getChanFactory(type_ reflect.Type, buffer int) factoryFunc {
+	return func(ServiceResolver) (any, error) {
+		return reflect.MakeChan(type_, buffer).Interface(), nil
+	}
+}
+
+// A ServiceType is a service type to register an implementation as with [RegisterAs].
+type ServiceType struct {
+	type_ reflect.Type
+}
+
+// As returns the [ServiceType] for the service type Service.
+func As[Service any]() ServiceType {
+	return ServiceType{reflect.TypeFor[Service]()}
+}
+
+// RegisterAs registers the type Impl like [RegisterType] as the implementation of each of the
+// given service types, which share a single registration: a Singleton is created once for all of
+// them and a Scoped service once per scope, so resolving a Reader and a Writer implemented by the
+// same *Store from the same scope provides the same *Store. Transient services are still created
+// every time they're resolved. Register Impl as its own service type by including As[Impl]().
+//
+// [ErrInvalidImplementation] is returned when Impl can't be assigned to one of the service types,
+// in which case none of them are registered. Decorating one of the service types with
+// [RegisterDecorator] decorates the shared instance for that service type only.
+func RegisterAs[Impl any](services *ServiceCollection, lifetime ServiceLifetime, serviceTypes ...ServiceType) error {
+	if services == nil {
+		return errors.New("cannot register types to a nil ServiceProvider")
+	}
+	if len(serviceTypes) == 0 {
+		return errors.New("RegisterAs requires at least one service type")
+	}
+
+	implType := reflect.TypeFor[Impl]()
+	keys := make([]serviceKey, 0, len(serviceTypes))
+	for _, serviceType := range serviceTypes {
+		if serviceType.type_ == nil {
+			return errors.New("cannot register a zero ServiceType: use As to create one")
+		}
+		if !implType.AssignableTo(serviceType.type_) {
+			return fmt.Errorf("%w: %v is not assignable to %v", ErrInvalidImplementation, implType, serviceType.type_)
+		}
+		// Registering the same instance twice would only make ResolveAll return it twice.
+		if key := keyFor(serviceType.type_); !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+
+	if lifetime != Transient && implType.Kind() == reflect.Struct {
+		return ErrNonTransientStruct
+	}
+
+	factory, dependencies, err := getDefaultFactory(implType)
+	if err != nil {
+		return err
+	}
+
+	return services.addRegistrations(keys, &serviceRegistration{
+		lifetime:     lifetime,
+		implType:     implType,
+		factory:      factory,
+		dependencies: dependencies,
+	}, appendRegistration)
+}
+
+// RegisterFunc registers a factory to create the implementations of the service type Service
+// when instances are resolved from a [ServiceProvider] built from the given [ServiceCollection].
+// Registering multiple implementations of the same service type adds to the existing
+// registrations: [Resolve] provides the last implementation registered and [ResolveAll] provides
+// all of them in the order they were registered.
 func RegisterFunc[Service any, Impl any](
 	services *ServiceCollection,
 	lifetime ServiceLifetime,
 	factory func(ServiceResolver) (Impl, error),
+) error {
+	return registerFunc[Service](services, lifetime, "", factory, appendRegistration)
+}
+
+// TryRegisterFunc registers a factory like [RegisterFunc] but only if no implementation is
+// already registered for the service type Service.
+func TryRegisterFunc[Service any, Impl any](
+	services *ServiceCollection,
+	lifetime ServiceLifetime,
+	factory func(ServiceResolver) (Impl, error),
+) error {
+	return registerFunc[Service](services, lifetime, "", factory, tryAddRegistration)
+}
+
+// ReplaceFunc registers a factory like [RegisterFunc] after removing every implementation already
+// registered for the service type Service. This is mostly useful for substituting fakes in tests.
+func ReplaceFunc[Service any, Impl any](
+	services *ServiceCollection,
+	lifetime ServiceLifetime,
+	factory func(ServiceResolver) (Impl, error),
+) error {
+	return registerFunc[Service](services, lifetime, "", factory, replaceRegistrations)
+}
+
+// RegisterFuncContext registers a factory like [RegisterFunc] which also receives the context the
+// service is resolved with, e.g. the deadline given to [ResolveContext] or the context of the
+// request, so that work such as dialing a database can be cancelled. When the service is resolved
+// without a context the factory receives [context.Background].
+func RegisterFuncContext[Service any, Impl any](
+	services *ServiceCollection,
+	lifetime ServiceLifetime,
+	factory func(context.Context, ServiceResolver) (Impl, error),
+) error {
+	return registerFunc[Service](services, lifetime, "", func(resolver ServiceResolver) (Impl, error) {
+		return factory(contextOf(resolver), resolver)
+	}, appendRegistration)
+}
+
+// RegisterKeyed registers a factory like [RegisterFunc] but under the given key so that multiple
+// implementations of the same service type can be registered side by side, e.g. a primary and a
+// replica *sql.DB. Keyed registrations are resolved with [ResolveKeyed] or by a field with an
+// `inject:"key=..."` tag and are never used to satisfy requests for the service type without a
+// key. Registering with the empty key is the same as calling [RegisterFunc].
+func RegisterKeyed[Service any, Impl any](
+	services *ServiceCollection,
+	lifetime ServiceLifetime,
+	key string,
+	factory func(ServiceResolver) (Impl, error),
+) error {
+	return registerFunc[Service](services, lifetime, key, factory, appendRegistration)
+}
+
+func registerFunc[Service any, Impl any](
+	services *ServiceCollection,
+	lifetime ServiceLifetime,
+	key string,
+	factory func(ServiceResolver) (Impl, error),
+	mode registrationMode,
 ) error {
 	if services == nil {
 		return errors.New("cannot register types to a nil ServiceProvider")
@@ -179,12 +588,23 @@ func RegisterFunc[Service any, Impl any](
 		return ErrNonTransientStruct
 	}
 
-	services.addRegistration(reflect.TypeFor[Service](), serviceRegistration{
+	return services.addRegistration(serviceKey{serviceType, key}, serviceRegistration{
 		lifetime: lifetime,
+		implType: implType,
 		factory: func(resolver ServiceResolver) (any, error) {
 			return factory(resolver)
 		},
-	})
+	}, mode)
+}
 
-	return nil
+// Remove removes every implementation registered for the service type Service, other than those
+// registered with a key. This is mostly useful for removing services with side effects in tests.
+func Remove[Service any](services *ServiceCollection) error {
+	if services == nil {
+		return errors.New("cannot remove types from a nil ServiceCollection")
+	}
+	return services.update(func(target *ServiceCollection) error {
+		delete(target.registrations, keyFor(reflect.TypeFor[Service]()))
+		return nil
+	})
 }
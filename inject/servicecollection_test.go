@@ -4,6 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -44,6 +47,69 @@ func TestServiceCollection(t *testing.T) {
 				}
 			})
 		}
+		t.Run("func returns error", func(t *testing.T) {
+			services := ServiceCollection{}
+			err := RegisterType(&services, Transient, func() {})
+			if !errors.Is(err, ErrUnsupportedType) {
+				t.Fatalf("expected %q; got %q", ErrUnsupportedType, err)
+			}
+		})
+
+		t.Run("nil interface returns error", func(t *testing.T) {
+			services := ServiceCollection{}
+			err := RegisterType[fooer](&services, Transient, nil)
+			if !errors.Is(err, ErrUnsupportedType) {
+				t.Fatalf("expected %q; got %q", ErrUnsupportedType, err)
+			}
+		})
+
+		t.Run("pointers to non-structs are resolved as pointers to new values", func(t *testing.T) {
+			services := ServiceCollection{}
+			RegisterType(&services, Transient, new(int))
+			RegisterType(&services, Scoped, new(namedMap))
+			RegisterType(&services, Singleton, new([]string))
+			provider, err := services.Build()
+			if err != nil {
+				t.Fatalf("unexpected error from ServiceCollection.Build: %q", err)
+			}
+			if i := MustResolve[*int](&provider); i == nil || *i != 0 {
+				t.Fatalf("expected a pointer to 0; got %v", i)
+			}
+			scope := provider.NewScope()
+			if m := MustResolve[*namedMap](&scope); m == nil || *m == nil {
+				t.Fatalf("expected a pointer to an empty map; got %v", m)
+			}
+			if s := MustResolve[*[]string](&provider); s == nil || *s == nil {
+				t.Fatalf("expected a pointer to an empty slice; got %v", s)
+			}
+		})
+
+		t.Run("pointer to func returns error", func(t *testing.T) {
+			services := ServiceCollection{}
+			err := RegisterType(&services, Transient, new(func()))
+			if !errors.Is(err, ErrUnsupportedType) {
+				t.Fatalf("expected %q; got %q", ErrUnsupportedType, err)
+			}
+		})
+
+		for _, tt := range []struct {
+			name  string
+			value any
+		}{
+			{name: "map", value: namedMap{}},
+			{name: "slice", value: namedSlice{}},
+			{name: "chan", value: make(namedChan)},
+			{name: "named int", value: namedInt(0)},
+			{name: "named string", value: namedString("")},
+		} {
+			t.Run(fmt.Sprintf("%s does not return error", tt.name), func(t *testing.T) {
+				services := ServiceCollection{}
+				err := RegisterType(&services, Transient, tt.value)
+				if err != nil {
+					t.Fatalf("unexpected error %q", err)
+				}
+			})
+		}
 	})
 
 	t.Run("RegisterFunc", func(t *testing.T) {
@@ -101,30 +167,226 @@ func TestServiceCollection(t *testing.T) {
 		}
 	})
 
+	t.Run("RegisterKeyed", func(t *testing.T) {
+
+		t.Run("keyed registration does not satisfy unkeyed request", func(t *testing.T) {
+			services := ServiceCollection{}
+			RegisterKeyed[*structWithUnexportedFields](&services, Singleton, "replica", func(ServiceResolver) (*structWithUnexportedFields, error) {
+				return &structWithUnexportedFields{}, nil
+			})
+			provider, _ := services.Build()
+			if _, err := provider.Resolve(reflect.TypeFor[*structWithUnexportedFields]()); err == nil {
+				t.Fatal("expected error; got <nil>")
+			}
+		})
+
+		t.Run("keyed registrations are distinct", func(t *testing.T) {
+			services := ServiceCollection{}
+			for _, id := range []int{1, 2} {
+				RegisterKeyed[*structWithUnexportedFields](&services, Singleton, fmt.Sprint(id), func(ServiceResolver) (*structWithUnexportedFields, error) {
+					return &structWithUnexportedFields{id: id}, nil
+				})
+			}
+			provider, _ := services.Build()
+			for _, id := range []int{1, 2} {
+				resolved, err := provider.ResolveKeyed(reflect.TypeFor[*structWithUnexportedFields](), fmt.Sprint(id))
+				if err != nil {
+					t.Fatalf("unexpected error from ServiceProvider.ResolveKeyed: %q", err)
+				}
+				if actual := resolved.(*structWithUnexportedFields).id; actual != id {
+					t.Fatalf("expected %d; got %d", id, actual)
+				}
+			}
+		})
+	})
+
+	t.Run("multiple registrations", func(t *testing.T) {
+
+		register := func(services *ServiceCollection, register func(*ServiceCollection, ServiceLifetime, func(ServiceResolver) (*structWithUnexportedFields, error)) error, id int) {
+			register(services, Scoped, func(ServiceResolver) (*structWithUnexportedFields, error) {
+				return &structWithUnexportedFields{id: id}, nil
+			})
+		}
+		resolveIDs := func(services *ServiceCollection) []int {
+			provider, _ := services.Build()
+			resolved, err := ResolveAll[*structWithUnexportedFields](&provider)
+			if err != nil {
+				t.Fatalf("unexpected error from ResolveAll: %q", err)
+			}
+			ids := make([]int, len(resolved))
+			for i, instance := range resolved {
+				ids[i] = instance.id
+			}
+			return ids
+		}
+
+		t.Run("ResolveAll returns all registrations in order", func(t *testing.T) {
+			services := ServiceCollection{}
+			register(&services, RegisterFunc[*structWithUnexportedFields], 1)
+			register(&services, RegisterFunc[*structWithUnexportedFields], 2)
+			if ids := resolveIDs(&services); !slices.Equal(ids, []int{1, 2}) {
+				t.Fatalf("expected %v; got %v", []int{1, 2}, ids)
+			}
+		})
+
+		t.Run("ResolveAll returns empty slice without registrations", func(t *testing.T) {
+			services := ServiceCollection{}
+			if ids := resolveIDs(&services); len(ids) != 0 {
+				t.Fatalf("expected no instances; got %v", ids)
+			}
+		})
+
+		t.Run("Resolve returns last registration", func(t *testing.T) {
+			services := ServiceCollection{}
+			register(&services, RegisterFunc[*structWithUnexportedFields], 1)
+			register(&services, RegisterFunc[*structWithUnexportedFields], 2)
+			provider, _ := services.Build()
+			resolved, _ := Resolve[*structWithUnexportedFields](&provider)
+			if resolved.id != 2 {
+				t.Fatalf("expected %d; got %d", 2, resolved.id)
+			}
+		})
+
+		t.Run("TryRegisterFunc does not register over existing registration", func(t *testing.T) {
+			services := ServiceCollection{}
+			register(&services, RegisterFunc[*structWithUnexportedFields], 1)
+			register(&services, TryRegisterFunc[*structWithUnexportedFields], 2)
+			if ids := resolveIDs(&services); !slices.Equal(ids, []int{1}) {
+				t.Fatalf("expected %v; got %v", []int{1}, ids)
+			}
+		})
+
+		t.Run("TryRegisterFunc registers without existing registration", func(t *testing.T) {
+			services := ServiceCollection{}
+			register(&services, TryRegisterFunc[*structWithUnexportedFields], 1)
+			if ids := resolveIDs(&services); !slices.Equal(ids, []int{1}) {
+				t.Fatalf("expected %v; got %v", []int{1}, ids)
+			}
+		})
+
+		t.Run("TryRegisterType does not register over existing registration", func(t *testing.T) {
+			services := ServiceCollection{}
+			register(&services, RegisterFunc[*structWithUnexportedFields], 1)
+			TryRegisterType(&services, Scoped, &structWithUnexportedFields{})
+			if ids := resolveIDs(&services); !slices.Equal(ids, []int{1}) {
+				t.Fatalf("expected %v; got %v", []int{1}, ids)
+			}
+		})
+
+		t.Run("ReplaceFunc replaces all existing registrations", func(t *testing.T) {
+			services := ServiceCollection{}
+			register(&services, RegisterFunc[*structWithUnexportedFields], 1)
+			register(&services, RegisterFunc[*structWithUnexportedFields], 2)
+			register(&services, ReplaceFunc[*structWithUnexportedFields], 3)
+			if ids := resolveIDs(&services); !slices.Equal(ids, []int{3}) {
+				t.Fatalf("expected %v; got %v", []int{3}, ids)
+			}
+		})
+
+		t.Run("ReplaceType replaces all existing registrations", func(t *testing.T) {
+			services := ServiceCollection{}
+			register(&services, RegisterFunc[*structWithUnexportedFields], 1)
+			ReplaceType(&services, Scoped, &structWithUnexportedFields{})
+			if ids := resolveIDs(&services); !slices.Equal(ids, []int{0}) {
+				t.Fatalf("expected %v; got %v", []int{0}, ids)
+			}
+		})
+
+		t.Run("Remove removes all existing registrations", func(t *testing.T) {
+			services := ServiceCollection{}
+			register(&services, RegisterFunc[*structWithUnexportedFields], 1)
+			register(&services, RegisterFunc[*structWithUnexportedFields], 2)
+			Remove[*structWithUnexportedFields](&services)
+			if ids := resolveIDs(&services); len(ids) != 0 {
+				t.Fatalf("expected no instances; got %v", ids)
+			}
+		})
+	})
+
+	t.Run("Build", func(t *testing.T) {
+
+		t.Run("missing field dependency returns error", func(t *testing.T) {
+			services := ServiceCollection{}
+			RegisterType(&services, Transient, &structWithInjectedFields{})
+			RegisterType[fooer](&services, Transient, assignableToFooer{})
+			_, err := services.Build()
+			if !errors.Is(err, ErrMissingDependency) {
+				t.Fatalf("expected %q; got %q", ErrMissingDependency, err)
+			}
+			if !strings.Contains(err.Error(), "[key=replica]") {
+				t.Fatalf("expected error to name the key; got %q", err)
+			}
+		})
+
+		t.Run("singleton depending on scoped returns error", func(t *testing.T) {
+			services := ServiceCollection{}
+			RegisterType(&services, Singleton, &dependsOnDependsOnFooer{})
+			RegisterType(&services, Transient, &dependsOnFooer{})
+			RegisterType[fooer](&services, Scoped, &assignableToFooer{})
+			_, err := services.Build()
+			if !errors.Is(err, ErrCaptiveDependency) {
+				t.Fatalf("expected %q; got %q", ErrCaptiveDependency, err)
+			}
+			chain := "*inject.dependsOnDependsOnFooer -> *inject.dependsOnFooer -> inject.fooer"
+			if !strings.Contains(err.Error(), chain) {
+				t.Fatalf("expected error to contain %q; got %q", chain, err)
+			}
+		})
+
+		t.Run("singleton depending on singleton through transient does not return error", func(t *testing.T) {
+			services := ServiceCollection{}
+			RegisterType(&services, Singleton, &dependsOnDependsOnFooer{})
+			RegisterType(&services, Transient, &dependsOnFooer{})
+			RegisterType[fooer](&services, Singleton, &assignableToFooer{})
+			if _, err := services.Build(); err != nil {
+				t.Fatalf("unexpected error %q", err)
+			}
+		})
+
+		t.Run("scoped depending on scoped does not return error", func(t *testing.T) {
+			services := ServiceCollection{}
+			RegisterType(&services, Scoped, &dependsOnFooer{})
+			RegisterType[fooer](&services, Scoped, &assignableToFooer{})
+			if _, err := services.Build(); err != nil {
+				t.Fatalf("unexpected error %q", err)
+			}
+		})
+
+		t.Run("circular field dependency returns error", func(t *testing.T) {
+			services := ServiceCollection{}
+			RegisterType(&services, Transient, &structWithCycleA{})
+			RegisterType(&services, Transient, &structWithCycleB{})
+			_, err := services.Build()
+			if !errors.Is(err, ErrCircularDependency) {
+				t.Fatalf("expected %q; got %q", ErrCircularDependency, err)
+			}
+		})
+	})
+
 	t.Run("Resolve", func(t *testing.T) {
 
 		t.Run("transient struct is resolved", func(t *testing.T) {
 
 			testCases := []struct {
 				name     string
-				services ServiceCollection
+				services *ServiceCollection
 			}{
 				{
 					name: "from type",
-					services: func() ServiceCollection {
+					services: func() *ServiceCollection {
 						services := ServiceCollection{}
 						RegisterType(&services, Transient, structWithUnexportedFields{})
-						return services
+						return &services
 					}(),
 				},
 				{
 					name: "from func",
-					services: func() ServiceCollection {
+					services: func() *ServiceCollection {
 						services := ServiceCollection{}
 						RegisterFunc[structWithUnexportedFields](&services, Transient, func(ServiceResolver) (structWithUnexportedFields, error) {
 							return structWithUnexportedFields{}, nil
 						})
-						return services
+						return &services
 					}(),
 				},
 			}
@@ -148,24 +410,24 @@ func TestServiceCollection(t *testing.T) {
 
 				testCases := []struct {
 					name     string
-					services ServiceCollection
+					services *ServiceCollection
 				}{
 					{
 						name: "from type",
-						services: func() ServiceCollection {
+						services: func() *ServiceCollection {
 							services := ServiceCollection{}
 							RegisterType(&services, Transient, &structWithUnexportedFields{})
-							return services
+							return &services
 						}(),
 					},
 					{
 						name: "from func",
-						services: func() ServiceCollection {
+						services: func() *ServiceCollection {
 							services := ServiceCollection{}
 							RegisterFunc[*structWithUnexportedFields](&services, Transient, func(ServiceResolver) (*structWithUnexportedFields, error) {
 								return &structWithUnexportedFields{}, nil
 							})
-							return services
+							return &services
 						}(),
 					},
 				}
@@ -189,6 +451,92 @@ func TestServiceCollection(t *testing.T) {
 			})
 		}
 
+		t.Run("map is resolved with non-nil map", func(t *testing.T) {
+			services := ServiceCollection{}
+			RegisterType(&services, Transient, namedMap{})
+			provider, _ := services.Build()
+			resolved, err := provider.Resolve(reflect.TypeFor[namedMap]())
+			if err != nil {
+				t.Fatalf("unexpected error from ServiceProvider.Resolve: %q", err)
+			}
+			if m := resolved.(namedMap); m == nil {
+				t.Fatalf("expected non-nil map; got nil")
+			}
+		})
+
+		t.Run("slice is resolved with non-nil slice", func(t *testing.T) {
+			services := ServiceCollection{}
+			RegisterType(&services, Transient, namedSlice{})
+			provider, _ := services.Build()
+			resolved, err := provider.Resolve(reflect.TypeFor[namedSlice]())
+			if err != nil {
+				t.Fatalf("unexpected error from ServiceProvider.Resolve: %q", err)
+			}
+			if s := resolved.(namedSlice); s == nil {
+				t.Fatalf("expected non-nil slice; got nil")
+			}
+		})
+
+		t.Run("chan is resolved with the registered buffer size", func(t *testing.T) {
+			services := ServiceCollection{}
+			RegisterType(&services, Transient, make(namedChan, 3))
+			provider, _ := services.Build()
+			resolved, err := provider.Resolve(reflect.TypeFor[namedChan]())
+			if err != nil {
+				t.Fatalf("unexpected error from ServiceProvider.Resolve: %q", err)
+			}
+			c := resolved.(namedChan)
+			if c == nil {
+				t.Fatalf("expected non-nil chan; got nil")
+			}
+			if cap(c) != 3 {
+				t.Fatalf("expected buffer size 3; got %d", cap(c))
+			}
+		})
+
+		t.Run("named primitive is resolved with zero value", func(t *testing.T) {
+			services := ServiceCollection{}
+			RegisterType(&services, Transient, namedInt(42))
+			provider, _ := services.Build()
+			resolved, err := provider.Resolve(reflect.TypeFor[namedInt]())
+			if err != nil {
+				t.Fatalf("unexpected error from ServiceProvider.Resolve: %q", err)
+			}
+			if resolved.(namedInt) != 0 {
+				t.Fatalf("expected 0; got %v", resolved)
+			}
+		})
+
+		t.Run("tagged exported fields are injected", func(t *testing.T) {
+			services := ServiceCollection{}
+			RegisterType(&services, Transient, &structWithInjectedFields{})
+			RegisterType[fooer](&services, Transient, assignableToFooer{})
+			RegisterKeyed[*structWithUnexportedFields](&services, Singleton, "replica", func(ServiceResolver) (*structWithUnexportedFields, error) {
+				return &structWithUnexportedFields{id: 2}, nil
+			})
+			provider, err := services.Build()
+			if err != nil {
+				t.Fatalf("unexpected error from ServiceCollection.Build: %q", err)
+			}
+			resolved, err := provider.Resolve(reflect.TypeFor[*structWithInjectedFields]())
+			if err != nil {
+				t.Fatalf("unexpected error from ServiceProvider.Resolve: %q", err)
+			}
+			instance := resolved.(*structWithInjectedFields)
+			if _, ok := instance.Fooer.(assignableToFooer); !ok {
+				t.Fatalf("expected %v; got %v", reflect.TypeFor[assignableToFooer](), reflect.TypeOf(instance.Fooer))
+			}
+			if instance.Replica == nil || instance.Replica.id != 2 {
+				t.Fatalf("expected keyed instance; got %v", instance.Replica)
+			}
+			if instance.Skipped != nil || instance.skipped != nil {
+				t.Fatalf("expected skipped fields to be nil")
+			}
+			if instance.Name != "" || instance.Untagged != nil {
+				t.Fatalf("expected untagged fields to be left as zero values")
+			}
+		})
+
 		t.Run("transient instances from the same provider are distinct", func(t *testing.T) {
 			services := ServiceCollection{}
 			RegisterType(&services, Transient, &structWithUnexportedFields{})
@@ -220,3 +568,171 @@ func TestServiceCollection(t *testing.T) {
 		})
 	})
 }
+
+func TestServiceCollectionFreeze(t *testing.T) {
+
+	register := map[string]func(*ServiceCollection) error{
+		"RegisterType": func(services *ServiceCollection) error {
+			return RegisterType(services, Transient, &assignableToFooer{})
+		},
+		"RegisterFunc": func(services *ServiceCollection) error {
+			return RegisterFunc[fooer](services, Transient, func(ServiceResolver) (*assignableToFooer, error) {
+				return &assignableToFooer{}, nil
+			})
+		},
+		"RegisterConstructor": func(services *ServiceCollection) error {
+			return RegisterConstructor[fooer](services, Transient, func() *assignableToFooer { return &assignableToFooer{} })
+		},
+		"RegisterDecorator": func(services *ServiceCollection) error {
+			return RegisterDecorator(services, func(inner fooer, _ ServiceResolver) (fooer, error) { return inner, nil })
+		},
+		"ConfigureFunc": func(services *ServiceCollection) error {
+			return ConfigureFunc(services, func(*testDBConfig) error { return nil })
+		},
+		"Remove": Remove[fooer],
+		"Install": func(services *ServiceCollection) error {
+			return services.Install(&Module{Name: "module"})
+		},
+	}
+
+	for name, register := range register {
+		t.Run(fmt.Sprintf("%s returns error after Build", name), func(t *testing.T) {
+			services := ServiceCollection{}
+			_, _ = services.Build()
+			if err := register(&services); !errors.Is(err, ErrCollectionFrozen) {
+				t.Fatalf("expected %q; got %q", ErrCollectionFrozen, err)
+			}
+		})
+	}
+
+	t.Run("registering returns error after Freeze", func(t *testing.T) {
+		services := ServiceCollection{}
+		services.Freeze()
+		if err := RegisterType(&services, Transient, &assignableToFooer{}); !errors.Is(err, ErrCollectionFrozen) {
+			t.Fatalf("expected %q; got %q", ErrCollectionFrozen, err)
+		}
+	})
+
+	t.Run("clones can be changed", func(t *testing.T) {
+		services := ServiceCollection{}
+		services.Freeze()
+		if err := RegisterType(services.Clone(), Transient, &assignableToFooer{}); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+	})
+
+	t.Run("registering from multiple goroutines is safe", func(t *testing.T) {
+		services := ServiceCollection{}
+		var wg sync.WaitGroup
+		for i := range 50 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_ = RegisterKeyed[fooer](&services, Transient, fmt.Sprint(i), func(ServiceResolver) (*assignableToFooer, error) {
+					return &assignableToFooer{}, nil
+				})
+				_ = services.Install(&Module{Name: fmt.Sprint(i)})
+			}()
+		}
+		wg.Wait()
+		if count := len(services.Graph().Services); count != 50 {
+			t.Fatalf("expected 50 registrations; got %d", count)
+		}
+	})
+}
+
+func TestRegisterAs(t *testing.T) {
+
+	t.Run("service types share instances", func(t *testing.T) {
+		for _, tc := range []struct {
+			lifetime ServiceLifetime
+			shared   bool
+		}{
+			{Singleton, true},
+			{Scoped, true},
+			{Transient, false},
+		} {
+			services := &ServiceCollection{}
+			if err := RegisterAs[*store](services, tc.lifetime, As[reader](), As[writer](), As[*store]()); err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+			provider, err := services.Build()
+			if err != nil {
+				t.Fatalf("unexpected error from ServiceCollection.Build: %q", err)
+			}
+			scope := provider.NewScope()
+			MustResolve[writer](&scope).Write("written")
+			if read := MustResolve[reader](&scope).Read(); (read == "written") != tc.shared {
+				t.Fatalf("expected %v to be shared %v; got %q", tc.lifetime, tc.shared, read)
+			}
+			if shared := MustResolve[*store](&scope) == MustResolve[reader](&scope); shared != tc.shared {
+				t.Fatalf("expected %v to be shared %v; got %v", tc.lifetime, tc.shared, shared)
+			}
+		}
+	})
+
+	t.Run("decorated service types share the undecorated instance", func(t *testing.T) {
+		for _, lifetime := range []ServiceLifetime{Singleton, Scoped} {
+			services := &ServiceCollection{}
+			RegisterAs[*store](services, lifetime, As[reader](), As[writer](), As[*store]())
+			RegisterDecorator(services, func(inner reader, _ ServiceResolver) (reader, error) {
+				return &prefixingReader{inner}, nil
+			})
+			provider := mustBuild(t, services)
+			scope := provider.NewScope()
+			MustResolve[writer](&scope).Write("written")
+			if read := MustResolve[reader](&scope).Read(); read != "prefixed written" {
+				t.Fatalf("expected %v to be shared; got %q", lifetime, read)
+			}
+			if MustResolve[*store](&scope) != MustResolve[writer](&scope) {
+				t.Fatalf("expected %v to be shared", lifetime)
+			}
+		}
+	})
+
+	t.Run("returns error when the implementation is not assignable", func(t *testing.T) {
+		services := &ServiceCollection{}
+		if err := RegisterAs[*store](services, Singleton, As[reader](), As[fooer]()); !errors.Is(err, ErrInvalidImplementation) {
+			t.Fatalf("expected %q; got %q", ErrInvalidImplementation, err)
+		}
+		if _, err := Resolve[reader](mustBuild(t, services)); !errors.Is(err, ErrNotRegistered) {
+			t.Fatalf("expected %q; got %q", ErrNotRegistered, err)
+		}
+	})
+
+	t.Run("returns error without service types", func(t *testing.T) {
+		if err := RegisterAs[*store](&ServiceCollection{}, Singleton); err == nil {
+			t.Fatal("expected error; got <nil>")
+		}
+		if err := RegisterAs[*store](&ServiceCollection{}, Singleton, ServiceType{}); err == nil {
+			t.Fatal("expected error; got <nil>")
+		}
+	})
+
+	t.Run("returns error for non-Transient structs", func(t *testing.T) {
+		if err := RegisterAs[assignableToFooer](&ServiceCollection{}, Scoped, As[fooer]()); !errors.Is(err, ErrNonTransientStruct) {
+			t.Fatalf("expected %q; got %q", ErrNonTransientStruct, err)
+		}
+	})
+
+	t.Run("repeated service types are registered once", func(t *testing.T) {
+		services := &ServiceCollection{}
+		RegisterAs[*store](services, Singleton, As[reader](), As[reader]())
+		all, err := ResolveAll[reader](mustBuild(t, services))
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if len(all) != 1 {
+			t.Fatalf("expected 1 instance; got %d", len(all))
+		}
+	})
+}
+
+func mustBuild(t *testing.T, services *ServiceCollection) *ServiceProvider {
+	t.Helper()
+	provider, err := services.Build()
+	if err != nil {
+		t.Fatalf("unexpected error from ServiceCollection.Build: %q", err)
+	}
+	return &provider
+}
@@ -0,0 +1,414 @@
+package inject
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrNotRegistered is returned when resolving a service for which no implementation is registered.
+var ErrNotRegistered = errors.New("no implementation registered")
+
+// ErrCircularDependency is returned when resolving a service requires resolving the same service
+// again, e.g. when the factory for A resolves B and the factory for B resolves A.
+var ErrCircularDependency = errors.New("circular dependency")
+
+// ErrCaptiveDependency is returned when a service with a shorter [ServiceLifetime] would be
+// captured by one with a longer lifetime, e.g. when a [Singleton] depends on a [Scoped] service.
+var ErrCaptiveDependency = errors.New("captive dependency")
+
+// A ServiceProvider is a factory from which services can be resolved by type.
+type ServiceProvider struct {
+	registrations map[serviceKey][]*serviceRegistration
+	options       *buildOptions
+	// root is the scope shared by the top level ServiceProvider and all of its descendant scopes.
+	// It holds the Singleton instances.
+	root *scope
+	// scope holds the Scoped instances for this ServiceProvider.
+	scope *scope
+	// source is the ServiceCollection the ServiceProvider was built from, not a copy of it, which
+	// Build froze so that it can't change. Override clones it rather than changing it.
+	source *ServiceCollection
+	// singleton is whether the ServiceProvider resolves the dependencies of a Singleton, whose
+	// Transient dependencies live as long as it does.
+	singleton bool
+}
+
+func newServiceProvider(registrations map[serviceKey][]*serviceRegistration, options *buildOptions) ServiceProvider {
+	root := newScope(options)
+	return ServiceProvider{
+		registrations: registrations,
+		options:       options,
+		root:          root,
+		scope:         root,
+	}
+}
+
+// NewScope creates a new ServiceProvider which will create distinct instances when resolving any
+// [Scoped] services. [Singleton] services resolved from the new ServiceProvider are shared with the
+// target ServiceProvider. Closing the new ServiceProvider only releases the instances it created.
+func (provider *ServiceProvider) NewScope() ServiceProvider {
+	if provider == nil {
+		return ServiceProvider{}
+	}
+	return ServiceProvider{
+		registrations: provider.registrations,
+		options:       provider.options,
+		root:          provider.root,
+		scope:         newScope(provider.options),
+		source:        provider.source,
+	}
+}
+
+// Override builds a new top level ServiceProvider from the [ServiceCollection] the target was built
+// from after making the registrations in override to a clone of it, like
+// [ServiceCollection.Override]. The new ServiceProvider has the same [BuildOption] values as the
+// target, followed by the given ones. It shares no instances with the target, and neither the
+// target nor its ServiceCollection is changed.
+func (provider *ServiceProvider) Override(
+	override func(*ServiceCollection) error,
+	opts ...BuildOption,
+) (ServiceProvider, error) {
+	if provider == nil || provider.source == nil {
+		return ServiceProvider{}, errors.New("cannot override ServiceProvider that was not built from a ServiceCollection")
+	}
+	options := *provider.options
+	options.onDispose = slices.Clone(options.onDispose)
+	options.observers = slices.Clone(options.observers)
+	for _, opt := range opts {
+		opt(&options)
+	}
+	clone := provider.source.Clone()
+	if err := override(clone); err != nil {
+		return ServiceProvider{}, err
+	}
+	return clone.build(&options)
+}
+
+// Resolve provides an instance of the requested type if one is registered.
+func (provider *ServiceProvider) Resolve(type_ reflect.Type) (any, error) {
+	if provider == nil {
+		return nil, errors.New("cannot resolve instances from nil ServiceProvider")
+	}
+	return provider.resolve(context.Background(), nil, keyFor(type_))
+}
+
+// ResolveKeyed provides an instance of the requested type registered under the given key if one is
+// registered.
+func (provider *ServiceProvider) ResolveKeyed(type_ reflect.Type, key string) (any, error) {
+	if provider == nil {
+		return nil, errors.New("cannot resolve instances from nil ServiceProvider")
+	}
+	return provider.resolve(context.Background(), nil, serviceKey{type_, key})
+}
+
+// ResolveAll provides an instance of every implementation registered for the requested type, in
+// the order they were registered.
+func (provider *ServiceProvider) ResolveAll(type_ reflect.Type) ([]any, error) {
+	if provider == nil {
+		return nil, errors.New("cannot resolve instances from nil ServiceProvider")
+	}
+	return provider.resolveAll(context.Background(), nil, keyFor(type_))
+}
+
+// Context returns [context.Background] because services resolved directly from a ServiceProvider
+// are not bounded by any context. Use [ServiceProvider.WithContext] or [ResolveContext] to bound
+// them.
+func (provider *ServiceProvider) Context() context.Context {
+	return context.Background()
+}
+
+// WithContext returns a [ServiceResolver] which resolves services from the ServiceProvider with the
+// given context. The context is given to factories registered with [RegisterFuncContext] and to
+// constructors that take a [context.Context], including those of the services they depend on, and
+// resolution fails once the context is done.
+func (provider *ServiceProvider) WithContext(ctx context.Context) ServiceResolver {
+	return resolution{provider: provider, ctx: ctx}
+}
+
+// Close releases every instance created by the ServiceProvider that implements [Disposer] or
+// [io.Closer], in the reverse of the order they were created. For a top level ServiceProvider this
+// includes the [Singleton] instances, which makes them unavailable to any descendant scopes. The
+// errors returned by the instances are joined into the returned error. Once a ServiceProvider is
+// closed every attempt to resolve services from it returns [ErrProviderClosed].
+//
+// Disposable [Transient] instances are released by the ServiceProvider they were resolved from, so
+// those resolved from a top level ServiceProvider are held until it's closed and each resolution
+// grows the memory it uses. Resolve them from a scope created with [ServiceProvider.NewScope]
+// instead, or use [StrictLifetimes] to make resolving them from the top level fail.
+func (provider *ServiceProvider) Close(ctx context.Context) error {
+	if provider == nil {
+		return errors.New("cannot close nil ServiceProvider")
+	}
+	if provider.scope == nil {
+		return nil
+	}
+	return provider.scope.close(ctx)
+}
+
+// resolve provides an instance of the requested service as a dependency of the services in path.
+func (provider *ServiceProvider) resolve(ctx context.Context, path []serviceKey, key serviceKey) (any, error) {
+	if provider.scope != nil && provider.scope.isClosed() {
+		return nil, newResolutionError(append(slices.Clip(path), key), ErrProviderClosed)
+	}
+	registrations := provider.registrations[key]
+	if len(registrations) == 0 {
+		if optional, ok := getOptional(key); ok {
+			dependency := keyFor(optional.dependency())
+			service, err := provider.resolve(ctx, path, dependency)
+			if err != nil {
+				if isNotRegistered(err, dependency) {
+					return reflect.Zero(key.type_).Interface(), nil
+				}
+				return nil, err
+			}
+			return optional.with(service), nil
+		}
+		if binder, ok := getBinder(key); ok {
+			// The bound value resolves its service later, once whatever is being resolved now
+			// has been created, so it doesn't inherit the path or the cancellation of the context.
+			// It keeps the values of the context, which record the instances being created, so
+			// that resolving one of them before it's finished fails rather than waiting forever.
+			return binder.bind(resolution{provider: provider, ctx: context.WithoutCancel(ctx)}), nil
+		}
+		return nil, newResolutionError(append(slices.Clip(path), key), ErrNotRegistered)
+	}
+	return provider.resolveRegistration(ctx, path, key, registrations[len(registrations)-1])
+}
+
+// resolveAll provides an instance of every implementation of the requested service as a dependency
+// of the services in path.
+func (provider *ServiceProvider) resolveAll(ctx context.Context, path []serviceKey, key serviceKey) ([]any, error) {
+	if provider.scope != nil && provider.scope.isClosed() {
+		return nil, newResolutionError(append(slices.Clip(path), key), ErrProviderClosed)
+	}
+	registrations := provider.registrations[key]
+	services := make([]any, 0, len(registrations))
+	for _, registration := range registrations {
+		service, err := provider.resolveRegistration(ctx, path, key, registration)
+		if err != nil {
+			return nil, err
+		}
+		services = append(services, service)
+	}
+	return services, nil
+}
+
+// resolveRegistration provides an instance of the given registration for the requested service,
+// attributing any error, including the context being done, to the path of services being resolved.
+func (provider *ServiceProvider) resolveRegistration(
+	ctx context.Context,
+	path []serviceKey,
+	key serviceKey,
+	registration *serviceRegistration,
+) (any, error) {
+	if slices.Contains(path, key) {
+		return nil, newResolutionError(append(slices.Clip(path), key), ErrCircularDependency)
+	}
+	// Clip the path before appending so that sibling dependencies never share a backing array.
+	path = append(slices.Clip(path), key)
+	if err := ctx.Err(); err != nil {
+		return nil, newResolutionError(path, err)
+	}
+	var observers []Observer
+	if provider.options != nil {
+		observers = provider.options.observers
+	}
+	if len(observers) == 0 {
+		service, _, err := provider.create(ctx, path, key, registration)
+		if err != nil {
+			return nil, newResolutionError(path, err)
+		}
+		return service, nil
+	}
+
+	event := ResolveEvent{
+		Service:  ServiceID{key.type_, key.key},
+		Lifetime: registration.lifetime,
+		ScopeID:  provider.scope.id,
+	}
+	for _, observer := range observers {
+		observer.BeforeResolve(event)
+	}
+	start := time.Now()
+	service, created, err := provider.create(ctx, path, key, registration)
+	if err != nil {
+		err = newResolutionError(path, err)
+	}
+	event.Duration = time.Since(start)
+	event.Err = err
+	event.CacheHit = err == nil && !created
+	for _, observer := range observers {
+		observer.AfterResolve(event)
+	}
+	return service, err
+}
+
+// create provides an instance of the given registration according to its lifetime, reporting
+// whether the factory was called to create it rather than an existing instance being shared.
+func (provider *ServiceProvider) create(
+	ctx context.Context,
+	path []serviceKey,
+	key serviceKey,
+	registration *serviceRegistration,
+) (service any, created bool, err error) {
+	switch registration.lifetime {
+	case Transient:
+		if provider.scope == provider.root && !provider.singleton && provider.options != nil && provider.options.strictLifetimes {
+			service, err := provider.instantiateUntracked(ctx, path, key, registration)
+			return service, true, err
+		}
+		service, err := provider.instantiate(ctx, provider.scope, path, key, registration)
+		return service, true, err
+	case Scoped:
+		if provider.scope == provider.root && provider.options != nil && provider.options.strictLifetimes {
+			return nil, false, fmt.Errorf("%w: Scoped %v resolved from the top level ServiceProvider",
+				ErrCaptiveDependency, key)
+		}
+		service, err := provider.scope.getOrCreate(ctx, registration, func(ctx context.Context) (any, error) {
+			created = true
+			return provider.instantiate(ctx, provider.scope, path, key, registration)
+		})
+		return service, created, err
+	case Singleton:
+		// Singletons are shared by every scope so their dependencies must come from the root.
+		root := &ServiceProvider{
+			registrations: provider.registrations,
+			options:       provider.options,
+			root:          provider.root,
+			scope:         provider.root,
+			source:        provider.source,
+			singleton:     true,
+		}
+		// Singletons outlive the resolution that happens to create them, e.g. a request, so they
+		// aren't created with its cancellation or deadline.
+		service, err := provider.root.getOrCreate(ctx, registration, func(ctx context.Context) (any, error) {
+			created = true
+			service, err := root.instantiate(context.WithoutCancel(ctx), provider.root, path, key, registration)
+			if err == nil {
+				provider.root.share(service)
+			}
+			return service, err
+		})
+		return service, created, err
+	default:
+		panic("this code should be unreachable: please open a an issue at https://github.com/ttd2089/stahp/issues/new")
+	}
+}
+
+// instantiate creates a new instance of the given registration and tracks the instances that are
+// created in s for disposal.
+func (provider *ServiceProvider) instantiate(
+	ctx context.Context,
+	s *scope,
+	path []serviceKey,
+	key serviceKey,
+	registration *serviceRegistration,
+) (any, error) {
+	if registration.decorated != nil {
+		return provider.applyDecorators(ctx, s, path, key, registration)
+	}
+	service, err := invoke(ctx, provider, path, registration)
+	if err != nil {
+		return nil, err
+	}
+	if registration.disposes() {
+		if err := provider.track(s, service); err != nil {
+			return nil, err
+		}
+	}
+	return service, nil
+}
+
+// track remembers the given service for disposal by s unless it's a Singleton instance, which
+// belongs to the Singleton's registration even when another registration returns it, and is only
+// disposed if that registration owns it.
+func (provider *ServiceProvider) track(s *scope, service any) error {
+	if provider.root.isShared(service) {
+		return nil
+	}
+	return s.track(service)
+}
+
+// instantiateUntracked creates a new instance of the given Transient registration for the top level
+// ServiceProvider, which would hold any disposable instances until it's closed, so instead of
+// tracking them it disposes them and fails.
+func (provider *ServiceProvider) instantiateUntracked(
+	ctx context.Context,
+	path []serviceKey,
+	key serviceKey,
+	registration *serviceRegistration,
+) (any, error) {
+	s := newScope(provider.options)
+	service, err := provider.instantiate(ctx, s, path, key, registration)
+	if err == nil && len(s.disposables) > 0 {
+		err = fmt.Errorf("%w: disposable Transient %v resolved from the top level ServiceProvider",
+			ErrCaptiveDependency, key)
+	}
+	if err != nil {
+		return nil, errors.Join(err, s.close(context.WithoutCancel(ctx)))
+	}
+	return service, nil
+}
+
+// invoke calls the factory of the given registration with a resolution of its dependencies from
+// the given provider. A factory that returns after the context is done fails with the context's
+// error, even if it succeeded, so that the service that overran a deadline is the one reported,
+// and the instance is disposed unless it isn't the provider's to dispose.
+func invoke(ctx context.Context, provider *ServiceProvider, path []serviceKey, registration *serviceRegistration) (any, error) {
+	service, err := registration.factory(resolution{provider, path, ctx})
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		if !registration.disposes() {
+			return nil, err
+		}
+		return nil, errors.Join(err, dispose(context.WithoutCancel(ctx), service))
+	}
+	return service, nil
+}
+
+// A resolution is the [ServiceResolver] given to factories. It remembers the chain of services
+// being resolved so that circular dependencies are reported rather than recursing forever or
+// waiting on an instance that will never be finished, and the context they're resolved with.
+type resolution struct {
+	provider *ServiceProvider
+	path     []serviceKey
+	ctx      context.Context
+}
+
+func (r resolution) Resolve(type_ reflect.Type) (any, error) {
+	return r.provider.resolve(r.ctx, r.path, keyFor(type_))
+}
+
+func (r resolution) ResolveKeyed(type_ reflect.Type, key string) (any, error) {
+	return r.provider.resolve(r.ctx, r.path, serviceKey{type_, key})
+}
+
+func (r resolution) ResolveAll(type_ reflect.Type) ([]any, error) {
+	return r.provider.resolveAll(r.ctx, r.path, keyFor(type_))
+}
+
+func (r resolution) Context() context.Context {
+	return r.ctx
+}
+
+func (r resolution) WithContext(ctx context.Context) ServiceResolver {
+	return resolution{r.provider, r.path, ctx}
+}
+
+func formatPath(path []serviceKey) string {
+	var sb strings.Builder
+	for i, key := range path {
+		if i > 0 {
+			sb.WriteString(" -> ")
+		}
+		sb.WriteString(key.String())
+	}
+	return sb.String()
+}
@@ -0,0 +1,503 @@
+package inject
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestServiceProvider(t *testing.T) {
+
+	t.Run("Resolve", func(t *testing.T) {
+
+		t.Run("scoped factory can resolve another scoped service", func(t *testing.T) {
+			services := ServiceCollection{}
+			RegisterType(&services, Scoped, &structWithUnexportedFields{})
+			RegisterFunc[fooer](&services, Scoped, func(r ServiceResolver) (*assignableToFooer, error) {
+				if _, err := Resolve[*structWithUnexportedFields](r); err != nil {
+					return nil, err
+				}
+				return &assignableToFooer{}, nil
+			})
+			provider, _ := services.Build()
+
+			done := make(chan error)
+			go func() {
+				_, err := Resolve[fooer](&provider)
+				done <- err
+			}()
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Fatalf("unexpected error: %q", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("nested scoped resolution did not complete")
+			}
+		})
+
+		t.Run("concurrent scoped resolutions share one instance", func(t *testing.T) {
+			var calls atomic.Int32
+			services := ServiceCollection{}
+			RegisterFunc[*structWithUnexportedFields](&services, Scoped, func(ServiceResolver) (*structWithUnexportedFields, error) {
+				calls.Add(1)
+				time.Sleep(10 * time.Millisecond)
+				return &structWithUnexportedFields{}, nil
+			})
+			provider, _ := services.Build()
+
+			const n = 16
+			instances := make([]*structWithUnexportedFields, n)
+			var wg sync.WaitGroup
+			for i := range n {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					instances[i], _ = Resolve[*structWithUnexportedFields](&provider)
+				}()
+			}
+			wg.Wait()
+
+			if c := calls.Load(); c != 1 {
+				t.Fatalf("expected factory to be called once; got %d", c)
+			}
+			for _, instance := range instances {
+				if instance != instances[0] {
+					t.Fatalf("scoped instances are distinct: %p %p", instances[0], instance)
+				}
+			}
+		})
+
+		t.Run("singleton instances from the same provider are the same", func(t *testing.T) {
+			services := ServiceCollection{}
+			RegisterType(&services, Singleton, &structWithUnexportedFields{})
+			provider, _ := services.Build()
+			a, _ := Resolve[*structWithUnexportedFields](&provider)
+			b, _ := Resolve[*structWithUnexportedFields](&provider)
+			if a != b {
+				t.Fatalf("singleton instances are distinct: %p %p", a, b)
+			}
+		})
+
+		t.Run("failed construction is retried", func(t *testing.T) {
+			fail := true
+			services := ServiceCollection{}
+			RegisterFunc[*structWithUnexportedFields](&services, Scoped, func(ServiceResolver) (*structWithUnexportedFields, error) {
+				if fail {
+					return nil, errors.New("expected error")
+				}
+				return &structWithUnexportedFields{}, nil
+			})
+			provider, _ := services.Build()
+			if _, err := Resolve[*structWithUnexportedFields](&provider); err == nil {
+				t.Fatal("expected error; got <nil>")
+			}
+			fail = false
+			if _, err := Resolve[*structWithUnexportedFields](&provider); err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+		})
+
+		for _, lifetime := range []ServiceLifetime{Transient, Scoped, Singleton} {
+			t.Run(lifetime.String()+" self dependency returns error", func(t *testing.T) {
+				services := ServiceCollection{}
+				RegisterFunc[*structWithUnexportedFields](&services, lifetime, func(r ServiceResolver) (*structWithUnexportedFields, error) {
+					return Resolve[*structWithUnexportedFields](r)
+				})
+				provider, _ := services.Build()
+				_, err := provider.Resolve(reflect.TypeFor[*structWithUnexportedFields]())
+				if !errors.Is(err, ErrCircularDependency) {
+					t.Fatalf("expected %q; got %q", ErrCircularDependency, err)
+				}
+			})
+		}
+
+		t.Run("indirect circular dependency returns error", func(t *testing.T) {
+			services := ServiceCollection{}
+			RegisterFunc[fooer](&services, Scoped, func(r ServiceResolver) (*assignableToFooer, error) {
+				_, err := Resolve[*structWithUnexportedFields](r)
+				return &assignableToFooer{}, err
+			})
+			RegisterFunc[*structWithUnexportedFields](&services, Scoped, func(r ServiceResolver) (*structWithUnexportedFields, error) {
+				_, err := Resolve[fooer](r)
+				return &structWithUnexportedFields{}, err
+			})
+			provider, _ := services.Build()
+			_, err := Resolve[fooer](&provider)
+			if !errors.Is(err, ErrCircularDependency) {
+				t.Fatalf("expected %q; got %q", ErrCircularDependency, err)
+			}
+		})
+	})
+}
+
+func TestServiceProviderClose(t *testing.T) {
+
+	t.Run("disposes instances in reverse creation order", func(t *testing.T) {
+		log := &disposalLog{}
+		services := ServiceCollection{}
+		RegisterFunc[io.Closer](&services, Singleton, func(ServiceResolver) (*closer, error) {
+			return &closer{name: "singleton", log: log}, nil
+		})
+		RegisterFunc[Disposer](&services, Scoped, func(r ServiceResolver) (*disposer, error) {
+			if _, err := Resolve[io.Closer](r); err != nil {
+				return nil, err
+			}
+			return &disposer{closer{name: "scoped", log: log}}, nil
+		})
+		RegisterFunc[*closer](&services, Transient, func(r ServiceResolver) (*closer, error) {
+			if _, err := Resolve[Disposer](r); err != nil {
+				return nil, err
+			}
+			return &closer{name: "transient", log: log}, nil
+		})
+		provider, _ := services.Build()
+		if _, err := Resolve[*closer](&provider); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if err := provider.Close(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		expected := []string{"transient", "scoped.Dispose", "singleton"}
+		if !slices.Equal(log.names, expected) {
+			t.Fatalf("expected %v; got %v", expected, log.names)
+		}
+	})
+
+	t.Run("joins errors from all instances", func(t *testing.T) {
+		errA, errB := errors.New("a"), errors.New("b")
+		log := &disposalLog{}
+		services := ServiceCollection{}
+		RegisterFunc[io.Closer](&services, Scoped, func(ServiceResolver) (*closer, error) {
+			return &closer{name: "a", log: log, err: errA}, nil
+		})
+		RegisterFunc[Disposer](&services, Scoped, func(ServiceResolver) (*disposer, error) {
+			return &disposer{closer{name: "b", log: log, err: errB}}, nil
+		})
+		provider, _ := services.Build()
+		Resolve[io.Closer](&provider)
+		Resolve[Disposer](&provider)
+		err := provider.Close(context.Background())
+		if !errors.Is(err, errA) || !errors.Is(err, errB) {
+			t.Fatalf("expected errors %q and %q; got %q", errA, errB, err)
+		}
+	})
+
+	t.Run("resolving from closed provider returns error", func(t *testing.T) {
+		services := ServiceCollection{}
+		RegisterType(&services, Scoped, &structWithUnexportedFields{})
+		provider, _ := services.Build()
+		provider.Close(context.Background())
+		_, err := provider.Resolve(reflect.TypeFor[*structWithUnexportedFields]())
+		if !errors.Is(err, ErrProviderClosed) {
+			t.Fatalf("expected %q; got %q", ErrProviderClosed, err)
+		}
+	})
+
+	t.Run("closing twice does not dispose twice", func(t *testing.T) {
+		log := &disposalLog{}
+		services := ServiceCollection{}
+		RegisterFunc[io.Closer](&services, Scoped, func(ServiceResolver) (*closer, error) {
+			return &closer{name: "a", log: log}, nil
+		})
+		provider, _ := services.Build()
+		Resolve[io.Closer](&provider)
+		provider.Close(context.Background())
+		provider.Close(context.Background())
+		if len(log.names) != 1 {
+			t.Fatalf("expected 1 disposal; got %v", log.names)
+		}
+	})
+}
+
+func TestServiceProviderNewScope(t *testing.T) {
+
+	t.Run("scoped instances from different scopes are distinct", func(t *testing.T) {
+		services := ServiceCollection{}
+		RegisterType(&services, Scoped, &structWithUnexportedFields{})
+		provider, _ := services.Build()
+		scope := provider.NewScope()
+		a, _ := Resolve[*structWithUnexportedFields](&provider)
+		b, _ := Resolve[*structWithUnexportedFields](&scope)
+		if a == b {
+			t.Fatalf("scoped instances are the same: %p %p", a, b)
+		}
+	})
+
+	t.Run("singleton instances from different scopes are the same", func(t *testing.T) {
+		services := ServiceCollection{}
+		RegisterType(&services, Singleton, &structWithUnexportedFields{})
+		provider, _ := services.Build()
+		scope := provider.NewScope()
+		a, _ := Resolve[*structWithUnexportedFields](&scope)
+		b, _ := Resolve[*structWithUnexportedFields](&provider)
+		if a != b {
+			t.Fatalf("singleton instances are distinct: %p %p", a, b)
+		}
+	})
+
+	t.Run("closing a scope does not dispose singletons", func(t *testing.T) {
+		log := &disposalLog{}
+		services := ServiceCollection{}
+		RegisterFunc[io.Closer](&services, Singleton, func(ServiceResolver) (*closer, error) {
+			return &closer{name: "singleton", log: log}, nil
+		})
+		RegisterFunc[Disposer](&services, Scoped, func(ServiceResolver) (*disposer, error) {
+			return &disposer{closer{name: "scoped", log: log}}, nil
+		})
+		provider, _ := services.Build()
+		scope := provider.NewScope()
+		Resolve[io.Closer](&scope)
+		Resolve[Disposer](&scope)
+		scope.Close(context.Background())
+		expected := []string{"scoped.Dispose"}
+		if !slices.Equal(log.names, expected) {
+			t.Fatalf("expected %v; got %v", expected, log.names)
+		}
+		if _, err := Resolve[io.Closer](&provider); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+	})
+}
+
+func TestStrictLifetimes(t *testing.T) {
+
+	t.Run("scoped resolved from root returns error", func(t *testing.T) {
+		services := ServiceCollection{}
+		RegisterType(&services, Scoped, &structWithUnexportedFields{})
+		provider, _ := services.Build(StrictLifetimes())
+		_, err := provider.Resolve(reflect.TypeFor[*structWithUnexportedFields]())
+		if !errors.Is(err, ErrCaptiveDependency) {
+			t.Fatalf("expected %q; got %q", ErrCaptiveDependency, err)
+		}
+	})
+
+	t.Run("scoped resolved by singleton factory returns error", func(t *testing.T) {
+		services := ServiceCollection{}
+		RegisterType(&services, Scoped, &structWithUnexportedFields{})
+		RegisterFunc[fooer](&services, Singleton, func(r ServiceResolver) (*assignableToFooer, error) {
+			_, err := Resolve[*structWithUnexportedFields](r)
+			return &assignableToFooer{}, err
+		})
+		provider, _ := services.Build(StrictLifetimes())
+		scope := provider.NewScope()
+		_, err := Resolve[fooer](&scope)
+		if !errors.Is(err, ErrCaptiveDependency) {
+			t.Fatalf("expected %q; got %q", ErrCaptiveDependency, err)
+		}
+	})
+
+	t.Run("scoped resolved from scope does not return error", func(t *testing.T) {
+		services := ServiceCollection{}
+		RegisterType(&services, Scoped, &structWithUnexportedFields{})
+		provider, _ := services.Build(StrictLifetimes())
+		scope := provider.NewScope()
+		if _, err := scope.Resolve(reflect.TypeFor[*structWithUnexportedFields]()); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+	})
+
+	t.Run("disposable transient resolved from root returns error and is disposed", func(t *testing.T) {
+		services := ServiceCollection{}
+		log := &disposalLog{}
+		RegisterFunc[*closer](&services, Transient, func(ServiceResolver) (*closer, error) {
+			return &closer{name: "transient", log: log}, nil
+		})
+		provider, _ := services.Build(StrictLifetimes())
+		_, err := Resolve[*closer](&provider)
+		if !errors.Is(err, ErrCaptiveDependency) {
+			t.Fatalf("expected %q; got %q", ErrCaptiveDependency, err)
+		}
+		if !slices.Equal(log.names, []string{"transient"}) {
+			t.Fatalf("expected %v; got %v", []string{"transient"}, log.names)
+		}
+	})
+
+	t.Run("transient resolved from root does not return error unless it is disposable", func(t *testing.T) {
+		services := ServiceCollection{}
+		RegisterType(&services, Transient, &structWithUnexportedFields{})
+		provider, _ := services.Build(StrictLifetimes())
+		if _, err := provider.Resolve(reflect.TypeFor[*structWithUnexportedFields]()); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+	})
+
+	t.Run("disposable transient resolved by singleton factory does not return error", func(t *testing.T) {
+		services := ServiceCollection{}
+		log := &disposalLog{}
+		RegisterFunc[*closer](&services, Transient, func(ServiceResolver) (*closer, error) {
+			return &closer{name: "transient", log: log}, nil
+		})
+		RegisterFunc[fooer](&services, Singleton, func(r ServiceResolver) (*assignableToFooer, error) {
+			_, err := Resolve[*closer](r)
+			return &assignableToFooer{}, err
+		})
+		provider, _ := services.Build(StrictLifetimes())
+		if _, err := Resolve[fooer](&provider); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if err := provider.Close(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if !slices.Equal(log.names, []string{"transient"}) {
+			t.Fatalf("expected %v; got %v", []string{"transient"}, log.names)
+		}
+	})
+}
+
+type testContextKey struct{}
+
+func TestResolveContext(t *testing.T) {
+
+	t.Run("gives the context to factories of nested dependencies", func(t *testing.T) {
+		services := ServiceCollection{}
+		RegisterFuncContext[namedString](&services, Transient, func(ctx context.Context, _ ServiceResolver) (namedString, error) {
+			value, _ := ctx.Value(testContextKey{}).(string)
+			return namedString(value), nil
+		})
+		RegisterConstructor[*dependsOnFooer](&services, Transient, func(ctx context.Context, name namedString) *dependsOnFooer {
+			if ctx.Value(testContextKey{}) != string(name) {
+				t.Errorf("expected %q; got %q", name, ctx.Value(testContextKey{}))
+			}
+			return &dependsOnFooer{}
+		})
+		provider, _ := services.Build()
+		ctx := context.WithValue(context.Background(), testContextKey{}, "value")
+
+		if _, err := ResolveContext[*dependsOnFooer](ctx, &provider); err != nil {
+			t.Fatalf("expected nil; got %q", err)
+		}
+		name, _ := ResolveContext[namedString](ctx, &provider)
+		if name != "value" {
+			t.Fatalf("expected %q; got %q", "value", name)
+		}
+	})
+
+	t.Run("gives factories the background context when resolving without one", func(t *testing.T) {
+		services := ServiceCollection{}
+		RegisterFuncContext[namedString](&services, Transient, func(ctx context.Context, _ ServiceResolver) (namedString, error) {
+			if ctx != context.Background() {
+				t.Errorf("expected context.Background(); got %v", ctx)
+			}
+			return "", nil
+		})
+		provider, _ := services.Build()
+
+		if _, err := Resolve[namedString](&provider); err != nil {
+			t.Fatalf("expected nil; got %q", err)
+		}
+	})
+
+	t.Run("returns error when the context is done", func(t *testing.T) {
+		services := ServiceCollection{}
+		RegisterType(&services, Singleton, &assignableToFooer{})
+		provider, _ := services.Build()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := ResolveContext[*assignableToFooer](ctx, &provider)
+
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected %q; got %q", context.Canceled, err)
+		}
+		if _, err := Resolve[*assignableToFooer](&provider); err != nil {
+			t.Fatalf("expected nil; got %q", err)
+		}
+	})
+
+	t.Run("reports the factory that exceeded the deadline in the path", func(t *testing.T) {
+		log := &disposalLog{}
+		services := ServiceCollection{}
+		RegisterFunc[*disposer](&services, Scoped, func(ServiceResolver) (*disposer, error) {
+			time.Sleep(20 * time.Millisecond)
+			return &disposer{closer{name: "slow", log: log}}, nil
+		})
+		RegisterConstructor[*dependsOnFooer](&services, Scoped, func(*disposer) *dependsOnFooer {
+			return &dependsOnFooer{}
+		})
+		provider, _ := services.Build()
+		scope := provider.NewScope()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+		defer cancel()
+
+		_, err := ResolveContext[*dependsOnFooer](ctx, &scope)
+
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected %q; got %q", context.DeadlineExceeded, err)
+		}
+		var resolutionErr *ResolutionError
+		if !errors.As(err, &resolutionErr) {
+			t.Fatalf("expected *ResolutionError; got %T", err)
+		}
+		expected := []ServiceID{{Type: reflect.TypeFor[*dependsOnFooer]()}, {Type: reflect.TypeFor[*disposer]()}}
+		if !slices.Equal(resolutionErr.Path, expected) {
+			t.Fatalf("expected %v; got %v", expected, resolutionErr.Path)
+		}
+		if !slices.Equal(log.names, []string{"slow.Dispose"}) {
+			t.Fatalf("expected the late instance to be disposed; got %q", log.names)
+		}
+	})
+
+	t.Run("creates Singletons without the cancellation of the context", func(t *testing.T) {
+		services := ServiceCollection{}
+		RegisterFuncContext[*assignableToFooer](&services, Singleton, func(ctx context.Context, _ ServiceResolver) (*assignableToFooer, error) {
+			if _, ok := ctx.Deadline(); ok || ctx.Done() != nil {
+				t.Errorf("expected a context without cancellation")
+			}
+			if ctx.Value(testContextKey{}) != "value" {
+				t.Errorf("expected %q; got %q", "value", ctx.Value(testContextKey{}))
+			}
+			return &assignableToFooer{}, nil
+		})
+		provider, _ := services.Build()
+		ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), testContextKey{}, "value"), time.Minute)
+		defer cancel()
+
+		if _, err := ResolveContext[*assignableToFooer](ctx, &provider); err != nil {
+			t.Fatalf("expected nil; got %q", err)
+		}
+	})
+
+	t.Run("waiters create the instance again when the creator's context is done", func(t *testing.T) {
+		started := make(chan struct{}, 2)
+		release := make(chan struct{})
+		services := ServiceCollection{}
+		RegisterFuncContext[*structWithUnexportedFields](&services, Scoped, func(ctx context.Context, _ ServiceResolver) (*structWithUnexportedFields, error) {
+			started <- struct{}{}
+			<-release
+			return &structWithUnexportedFields{}, nil
+		})
+		provider, _ := services.Build()
+		scope := provider.NewScope()
+		cancelled, cancel := context.WithCancel(context.Background())
+
+		creatorErr := make(chan error)
+		go func() {
+			_, err := ResolveContext[*structWithUnexportedFields](cancelled, &scope)
+			creatorErr <- err
+		}()
+		<-started
+		waiterErr := make(chan error)
+		go func() {
+			_, err := ResolveContext[*structWithUnexportedFields](context.Background(), &scope)
+			waiterErr <- err
+		}()
+		// Give the waiter a chance to start waiting on the creator.
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+		close(release)
+
+		if err := <-creatorErr; !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected %q; got %q", context.Canceled, err)
+		}
+		if err := <-waiterErr; err != nil {
+			t.Fatalf("expected nil; got %q", err)
+		}
+	})
+}
@@ -1,5 +1,10 @@
 package inject
 
+import (
+	"context"
+	"sync"
+)
+
 type fooer interface {
 	Foo()
 }
@@ -8,6 +13,110 @@ type assignableToFooer struct{}
 
 func (assignableToFooer) Foo() {}
 
+type reader interface {
+	Read() string
+}
+
+type writer interface {
+	Write(string)
+}
+
+// store implements both reader and writer.
+type store struct {
+	value string
+}
+
+func (s *store) Read() string { return s.value }
+
+func (s *store) Write(value string) { s.value = value }
+
 type structWithUnexportedFields struct {
 	id int
 }
+
+type namedInt int
+
+type namedString string
+
+type namedMap map[string]int
+
+type namedSlice []string
+
+type namedChan chan int
+
+// disposalLog records the names of disposed services in the order they were disposed.
+type disposalLog struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (log *disposalLog) add(name string) {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	log.names = append(log.names, name)
+}
+
+type closer struct {
+	name string
+	log  *disposalLog
+	err  error
+}
+
+func (c *closer) Close() error {
+	c.log.add(c.name)
+	return c.err
+}
+
+type disposer struct {
+	closer
+}
+
+func (d *disposer) Dispose(context.Context) error {
+	d.log.add(d.name + ".Dispose")
+	return d.err
+}
+
+type structWithInjectedFields struct {
+	Fooer    fooer                       `inject:""`
+	Replica  *structWithUnexportedFields `inject:"key=replica"`
+	Skipped  *structWithUnexportedFields `inject:"-"`
+	Name     string
+	Untagged fooer
+	skipped  *structWithUnexportedFields
+}
+
+type structWithCycleA struct {
+	B *structWithCycleB `inject:""`
+}
+
+type structWithCycleB struct {
+	A *structWithCycleA `inject:""`
+}
+
+// namingFooer is a fooer whose name records how it was decorated.
+type namingFooer struct {
+	name string
+}
+
+func (*namingFooer) Foo() {}
+
+type structWithLazyFields struct {
+	Lazy    Lazy[*structWithUnexportedFields]    `inject:""`
+	Factory Factory[*structWithUnexportedFields] `inject:""`
+}
+
+type lazyCycleA struct {
+	B *lazyCycleB `inject:""`
+}
+
+type lazyCycleB struct {
+	A Lazy[*lazyCycleA] `inject:""`
+}
+
+type dependsOnFooer struct {
+	Fooer fooer `inject:""`
+}
+
+type dependsOnDependsOnFooer struct {
+	Inner *dependsOnFooer `inject:""`
+}
@@ -0,0 +1,165 @@
+package inject
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrMissingDependency is returned by [ServiceCollection.Build] when a registered service depends
+// on a service for which no implementation is registered.
+var ErrMissingDependency = errors.New("missing dependency")
+
+// validate checks the known dependencies of the given registrations for services that aren't
+// registered and for cycles. Every problem that is found is included in the returned error.
+func validate(registrations map[serviceKey][]*serviceRegistration) error {
+	keys := sortedKeys(registrations)
+	var errs []error
+	for _, key := range keys {
+		for _, registration := range registrations[key] {
+			for _, dependency := range registration.dependencies {
+				if dependency.optional || isRegistered(registrations, dependency.serviceKey) {
+					continue
+				}
+				errs = append(errs, fmt.Errorf("%w: %s depends on %v which is not registered",
+					ErrMissingDependency, registration.describe(key), dependency.describe()))
+			}
+		}
+	}
+
+	// Find cycles with a depth first search, reporting each cycle from the first service in it
+	// that's visited.
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	states := make(map[serviceKey]int, len(registrations))
+	var path []serviceKey
+	var visit func(key serviceKey)
+	visit = func(key serviceKey) {
+		switch states[key] {
+		case visiting:
+			start := slices.Index(path, key)
+			errs = append(errs, fmt.Errorf("%w: %s", ErrCircularDependency, formatPath(append(slices.Clone(path[start:]), key))))
+			return
+		case visited:
+			return
+		}
+		states[key] = visiting
+		path = append(path, key)
+		for _, registration := range registrations[key] {
+			for _, dependency := range registration.dependencies {
+				// Optional services are resolved with the service that depends on them so they're
+				// part of any cycle.
+				visit(underlying(dependency.serviceKey))
+			}
+		}
+		path = path[:len(path)-1]
+		states[key] = visited
+	}
+	for _, key := range keys {
+		visit(key)
+	}
+
+	// Singletons are created once for the whole application so any Scoped service they depend on,
+	// directly or through Transient services, would be captured from whichever scope resolved the
+	// Singleton first.
+	for _, key := range keys {
+		for _, registration := range registrations[key] {
+			if registration.lifetime != Singleton {
+				continue
+			}
+			if chain, ok := findCaptive(registrations, []serviceKey{key}, registration); ok {
+				errs = append(errs, fmt.Errorf("%w: Singleton %s depends on Scoped %v: %s",
+					ErrCaptiveDependency, registration.describe(key), chain[len(chain)-1], formatPath(chain)))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// findCaptive searches the known dependencies of the given registration for a Scoped service that
+// would be resolved from the same scope as the registration. The returned chain starts with path
+// and ends with the Scoped service.
+func findCaptive(
+	registrations map[serviceKey][]*serviceRegistration,
+	path []serviceKey,
+	registration *serviceRegistration,
+) ([]serviceKey, bool) {
+	for _, dependency := range registration.dependencies {
+		key := underlying(dependency.serviceKey)
+		// Services like Lazy are bound to the scope of the service they're injected into so the
+		// service they resolve is just as captive.
+		if binder, ok := getBinder(key); ok && len(registrations[key]) == 0 {
+			key = keyFor(binder.dependency())
+		}
+		registered := registrations[key]
+		// Missing dependencies and cycles are reported separately.
+		if len(registered) == 0 || slices.Contains(path, key) {
+			continue
+		}
+		chain := append(slices.Clip(path), key)
+		switch resolved := registered[len(registered)-1]; resolved.lifetime {
+		case Scoped:
+			return chain, true
+		case Transient:
+			if chain, ok := findCaptive(registrations, chain, resolved); ok {
+				return chain, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// isRegistered reports whether the given service can be resolved from the given registrations.
+// Services like [Lazy] that are bound to their resolver don't need to be registered themselves but
+// the service they resolve does. They aren't followed when looking for cycles though because
+// they're the way to break them. An [Optional] can always be resolved.
+func isRegistered(registrations map[serviceKey][]*serviceRegistration, key serviceKey) bool {
+	if len(registrations[key]) > 0 {
+		return true
+	}
+	if _, ok := getOptional(key); ok {
+		return true
+	}
+	if binder, ok := getBinder(key); ok {
+		return len(registrations[keyFor(binder.dependency())]) > 0
+	}
+	return false
+}
+
+// describe names the service the registration is for, and the module that made it if any.
+func (registration *serviceRegistration) describe(key serviceKey) string {
+	if registration.module == "" {
+		return key.String()
+	}
+	return fmt.Sprintf("%v (module %s)", key, registration.module)
+}
+
+func (dep dependency) describe() string {
+	if dep.field == "" {
+		return dep.serviceKey.String()
+	}
+	return fmt.Sprintf("%v (field %s)", dep.serviceKey, dep.field)
+}
+
+// sortedKeys returns the keys of the given registrations in a stable order so that diagnostics
+// are reported consistently.
+func sortedKeys(registrations map[serviceKey][]*serviceRegistration) []serviceKey {
+	keys := make([]serviceKey, 0, len(registrations))
+	for key := range registrations {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b serviceKey) int {
+		// Types from different packages can have the same name.
+		return cmp.Or(
+			strings.Compare(a.String(), b.String()),
+			strings.Compare(TypeName(a.type_), TypeName(b.type_)),
+		)
+	})
+	return keys
+}
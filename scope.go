@@ -0,0 +1,85 @@
+package stahp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/ttd2089/stahp/inject"
+)
+
+// ErrNoRequestScope is returned to the [Responder] of a route created with [RouteFrom] when the
+// request context does not carry a request scope, i.e. when the route is not wrapped with
+// [RequestScopes].
+var ErrNoRequestScope = errors.New("request context has no request scope")
+
+// A RequestScopesOption configures the middleware created by [RequestScopes].
+type RequestScopesOption func(*requestScopesOptions)
+
+type requestScopesOptions struct {
+	onCloseErr func(error, *http.Request)
+}
+
+// OnScopeCloseError sets the function that is called with the error, and the request, when closing
+// a request scope fails. Without this option the errors are ignored.
+func OnScopeCloseError(onCloseErr func(error, *http.Request)) RequestScopesOption {
+	return func(options *requestScopesOptions) {
+		options.onCloseErr = onCloseErr
+	}
+}
+
+// RequestScopes creates middleware that gives every request its own scope created from the given
+// [inject.ServiceProvider]. The scope is stored in the request context, where it can be retrieved
+// with [inject.FromContext], and it is closed when the wrapped handler returns.
+func RequestScopes(provider *inject.ServiceProvider, opts ...RequestScopesOption) func(http.Handler) http.Handler {
+	options := requestScopesOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scope := provider.NewScope()
+			defer func() {
+				// The request context may already be cancelled if the client went away but the
+				// scope still needs to release its resources.
+				err := scope.Close(context.WithoutCancel(r.Context()))
+				if err != nil && options.onCloseErr != nil {
+					options.onCloseErr(err, r)
+				}
+			}()
+			next.ServeHTTP(w, r.WithContext(inject.NewContext(r.Context(), &scope)))
+		})
+	}
+}
+
+// A ScopedTarget is a strongly-typed function like [Target] which is a method of, or otherwise
+// needs, a Handler resolved from the request scope. Method expressions such as
+// (*userHandler).getUser satisfy ScopedTarget.
+type ScopedTarget[Handler any, Req any, Resp any] func(Handler, context.Context, Req) (Resp, error)
+
+// RouteFrom generates an [http.HandlerFunc] like [Route] but resolves the Handler for the
+// [ScopedTarget] from the request scope created by [RequestScopes] for every request, with the
+// request context so that its factories are cancelled with the request. Errors resolving the
+// Handler are marshaled by the [Responder] like errors from the target.
+func RouteFrom[Handler any, Req any, Resp any](
+	target ScopedTarget[Handler, Req, Resp],
+	parser RequestParser[Req],
+	responder Responder[Resp],
+) http.HandlerFunc {
+	return Route(
+		func(ctx context.Context, req Req) (Resp, error) {
+			var zero Resp
+			scope, ok := inject.FromContext(ctx)
+			if !ok {
+				return zero, ErrNoRequestScope
+			}
+			handler, err := inject.ResolveContext[Handler](ctx, scope)
+			if err != nil {
+				return zero, err
+			}
+			return target(handler, ctx, req)
+		},
+		parser,
+		responder,
+	)
+}
@@ -0,0 +1,142 @@
+package stahp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ttd2089/stahp/inject"
+)
+
+type scopedRepo struct {
+	closed bool
+	err    error
+}
+
+func (repo *scopedRepo) Close() error {
+	repo.closed = true
+	return repo.err
+}
+
+type scopedHandler struct {
+	repo *scopedRepo
+}
+
+func (h *scopedHandler) get(_ context.Context, _ struct{}) (*scopedRepo, error) {
+	return h.repo, nil
+}
+
+func TestRouteFrom(t *testing.T) {
+
+	newProvider := func() inject.ServiceProvider {
+		services := inject.ServiceCollection{}
+		inject.RegisterType(&services, inject.Scoped, &scopedRepo{})
+		inject.RegisterFunc[*scopedHandler](&services, inject.Transient, func(r inject.ServiceResolver) (*scopedHandler, error) {
+			repo, err := inject.Resolve[*scopedRepo](r)
+			return &scopedHandler{repo}, err
+		})
+		provider, _ := services.Build()
+		return provider
+	}
+
+	newResponder := func(resps *[]*scopedRepo, errs *[]error) Responder[*scopedRepo] {
+		return NewResponder(
+			func(resp *scopedRepo, w http.ResponseWriter, _ *http.Request) {
+				*resps = append(*resps, resp)
+			},
+			func(err error, w http.ResponseWriter, _ *http.Request) {
+				*errs = append(*errs, err)
+			},
+			func(err error, w http.ResponseWriter, _ *http.Request) {
+				*errs = append(*errs, err)
+			},
+		)
+	}
+
+	t.Run("resolves handlers from a scope per request and closes it", func(t *testing.T) {
+		provider := newProvider()
+		var resps []*scopedRepo
+		var errs []error
+		handler := RequestScopes(&provider)(RouteFrom((*scopedHandler).get, NoReqParser, newResponder(&resps, &errs)))
+
+		for range 2 {
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		}
+
+		if len(errs) != 0 {
+			t.Fatalf("unexpected errors: %v", errs)
+		}
+		if resps[0] == resps[1] {
+			t.Fatalf("requests shared a scoped instance: %p %p", resps[0], resps[1])
+		}
+		for _, repo := range resps {
+			if !repo.closed {
+				t.Fatalf("request scope was not closed")
+			}
+		}
+	})
+
+	t.Run("returns error without request scope", func(t *testing.T) {
+		var resps []*scopedRepo
+		var errs []error
+		handler := RouteFrom((*scopedHandler).get, NoReqParser, newResponder(&resps, &errs))
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if len(errs) != 1 || !errors.Is(errs[0], ErrNoRequestScope) {
+			t.Fatalf("expected %q; got %v", ErrNoRequestScope, errs)
+		}
+	})
+}
+
+func TestRequestScopes(t *testing.T) {
+
+	t.Run("reports errors closing the request scope", func(t *testing.T) {
+		closeErr := errors.New("close failed")
+		services := inject.ServiceCollection{}
+		inject.RegisterFunc[*scopedRepo](&services, inject.Scoped, func(inject.ServiceResolver) (*scopedRepo, error) {
+			return &scopedRepo{err: closeErr}, nil
+		})
+		provider, _ := services.Build()
+		var reported []error
+		var paths []string
+		onCloseErr := func(err error, r *http.Request) {
+			reported = append(reported, err)
+			paths = append(paths, r.URL.Path)
+		}
+		handler := RequestScopes(&provider, OnScopeCloseError(onCloseErr))(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			scope, _ := inject.FromContext(r.Context())
+			inject.Resolve[*scopedRepo](scope)
+		}))
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/repos", nil))
+
+		if len(reported) != 1 || !errors.Is(reported[0], closeErr) {
+			t.Fatalf("expected %q; got %v", closeErr, reported)
+		}
+		if paths[0] != "/repos" {
+			t.Fatalf("expected %q; got %q", "/repos", paths[0])
+		}
+	})
+
+	t.Run("ignores errors closing the request scope without a handler", func(t *testing.T) {
+		services := inject.ServiceCollection{}
+		inject.RegisterFunc[*scopedRepo](&services, inject.Scoped, func(inject.ServiceResolver) (*scopedRepo, error) {
+			return &scopedRepo{err: errors.New("close failed")}, nil
+		})
+		provider, _ := services.Build()
+		var repo *scopedRepo
+		handler := RequestScopes(&provider)(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			scope, _ := inject.FromContext(r.Context())
+			repo, _ = inject.Resolve[*scopedRepo](scope)
+		}))
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if !repo.closed {
+			t.Fatalf("request scope was not closed")
+		}
+	})
+}
@@ -0,0 +1,172 @@
+package stahpcli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// The formats responses can be written in.
+const (
+	formatJSON  = "json"
+	formatTable = "table"
+)
+
+// writeResponse writes the given response to w in the given format.
+func writeResponse(w io.Writer, format string, resp any) error {
+	if format == formatJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(resp)
+	}
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	if err := writeTable(table, reflect.ValueOf(resp)); err != nil {
+		return err
+	}
+	return table.Flush()
+}
+
+// writeTable writes a table with a row for every element of a slice or array, or for every field
+// or entry of a struct or map. Slices of structs have a column for every field. Anything else is
+// written as a single value.
+func writeTable(w io.Writer, v reflect.Value) error {
+	v = indirect(v)
+	if !v.IsValid() {
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		elemType := v.Type().Elem()
+		for elemType.Kind() == reflect.Pointer {
+			elemType = elemType.Elem()
+		}
+		if elemType.Kind() != reflect.Struct {
+			for i := range v.Len() {
+				if _, err := fmt.Fprintln(w, formatCell(v.Index(i))); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		fields := columns(elemType)
+		header := make([]string, len(fields))
+		for i, field := range fields {
+			header[i] = strings.ToUpper(field.name)
+		}
+		if err := writeRow(w, header); err != nil {
+			return err
+		}
+		for i := range v.Len() {
+			elem := indirect(v.Index(i))
+			row := make([]string, len(fields))
+			for j, field := range fields {
+				if elem.IsValid() {
+					row[j] = formatCell(fieldByIndex(elem, field.index))
+				}
+			}
+			if err := writeRow(w, row); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Struct:
+		for _, field := range columns(v.Type()) {
+			if err := writeRow(w, []string{field.name, formatCell(fieldByIndex(v, field.index))}); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		keys := v.MapKeys()
+		slices.SortFunc(keys, func(a, b reflect.Value) int {
+			return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+		})
+		for _, key := range keys {
+			if err := writeRow(w, []string{fmt.Sprint(key), formatCell(v.MapIndex(key))}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	_, err := fmt.Fprintln(w, formatCell(v))
+	return err
+}
+
+// A column is an exported field of a struct named like it's encoded as JSON.
+type column struct {
+	name  string
+	index []int
+}
+
+func columns(structType reflect.Type) []column {
+	var columns []column
+	for _, field := range reflect.VisibleFields(structType) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			if tag == "-" {
+				continue
+			}
+			if tagName, _, _ := strings.Cut(tag, ","); tagName != "" {
+				name = tagName
+			}
+		}
+		columns = append(columns, column{name, field.Index})
+	}
+	return columns
+}
+
+func writeRow(w io.Writer, cells []string) error {
+	_, err := fmt.Fprintln(w, strings.Join(cells, "\t"))
+	return err
+}
+
+// formatCell formats scalars as text and anything else as JSON so that every cell is one line.
+func formatCell(v reflect.Value) string {
+	v = indirect(v)
+	if !v.IsValid() {
+		return ""
+	}
+	if stringer, ok := v.Interface().(fmt.Stringer); ok {
+		return stringer.String()
+	}
+	switch v.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		encoded, err := json.Marshal(v.Interface())
+		if err != nil {
+			return fmt.Sprint(v.Interface())
+		}
+		return string(encoded)
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// indirect follows pointers and interfaces to the value they refer to, which is invalid for nil.
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// fieldByIndex is like [reflect.Value.FieldByIndex] but returns the invalid value rather than
+// panicking when the field is promoted through a nil embedded pointer.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	field, err := v.FieldByIndexErr(index)
+	if err != nil {
+		return reflect.Value{}
+	}
+	return field
+}
@@ -0,0 +1,207 @@
+package stahpcli
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A requestParser builds the request of a subcommand from its flags, its arguments, and stdin.
+type requestParser[Req any] struct {
+	// fields are the fields of a struct request, or nil when the request isn't a struct.
+	fields []*fieldFlag
+}
+
+// A fieldFlag is the flag for a field of a struct request. It remembers the values it's given so
+// that they can be applied after the request is decoded from stdin.
+type fieldFlag struct {
+	name   string
+	index  []int
+	type_  reflect.Type
+	values []string
+}
+
+func (f *fieldFlag) String() string {
+	// The flag package calls String on zero values to find defaults.
+	if f == nil || len(f.values) == 0 {
+		return ""
+	}
+	return f.values[len(f.values)-1]
+}
+
+func (f *fieldFlag) Set(value string) error {
+	// Check the value now so that the flag package reports which flag it was given to.
+	if err := setValue(reflect.New(f.type_).Elem(), value); err != nil {
+		return err
+	}
+	f.values = append(f.values, value)
+	return nil
+}
+
+func (f *fieldFlag) IsBoolFlag() bool {
+	return f.type_.Kind() == reflect.Bool
+}
+
+var (
+	durationType        = reflect.TypeFor[time.Duration]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// newRequestParser defines a flag in the given set for every field of Req if it's a struct, or a
+// pointer to one.
+func newRequestParser[Req any](flags *flag.FlagSet) (*requestParser[Req], error) {
+	parser := &requestParser[Req]{}
+	structType := reflect.TypeFor[Req]()
+	if structType.Kind() == reflect.Pointer {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return parser, nil
+	}
+	parser.fields = []*fieldFlag{}
+	for _, field := range reflect.VisibleFields(structType) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			if tag == "-" {
+				continue
+			}
+			if tagName, _, _ := strings.Cut(tag, ","); tagName != "" {
+				name = tagName
+			}
+		}
+		switch field.Type.Kind() {
+		case reflect.Chan, reflect.Func, reflect.UnsafePointer, reflect.Complex64, reflect.Complex128:
+			// These can't be decoded from JSON either.
+			continue
+		}
+		if flags.Lookup(name) != nil {
+			return nil, fmt.Errorf("field %s of %v conflicts with flag -%s", field.Name, structType, name)
+		}
+		f := &fieldFlag{name: name, index: field.Index, type_: field.Type}
+		flags.Var(f, name, fmt.Sprintf("the %s field of the request (`%s`)", name, describe(field.Type)))
+		parser.fields = append(parser.fields, f)
+	}
+	return parser, nil
+}
+
+// parse builds a request from JSON on the given stdin, if it's not nil, and then the flags that
+// were given or, for requests that aren't structs, the argument.
+func (parser *requestParser[Req]) parse(stdin io.Reader, args []string) (Req, error) {
+	var req Req
+	if stdin != nil {
+		if err := json.NewDecoder(stdin).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			return req, fmt.Errorf("%w: decoding request from stdin: %w", ErrUsage, err)
+		}
+	}
+
+	if parser.fields == nil {
+		switch {
+		case len(args) == 1:
+			if err := setValue(reflect.ValueOf(&req).Elem(), args[0]); err != nil {
+				return req, fmt.Errorf("%w: invalid argument %q: %w", ErrUsage, args[0], err)
+			}
+		case len(args) == 0 && stdin == nil:
+			return req, fmt.Errorf("%w: expected an argument or -stdin", ErrUsage)
+		case len(args) > 1:
+			return req, fmt.Errorf("%w: expected a single argument; got %d", ErrUsage, len(args))
+		}
+		return req, nil
+	}
+
+	if len(args) > 0 {
+		return req, fmt.Errorf("%w: unexpected arguments %q", ErrUsage, args)
+	}
+	v := reflect.ValueOf(&req).Elem()
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	for _, field := range parser.fields {
+		for _, value := range field.values {
+			if err := setValue(v.FieldByIndex(field.index), value); err != nil {
+				return req, fmt.Errorf("%w: invalid value %q for flag -%s: %w", ErrUsage, value, field.name, err)
+			}
+		}
+	}
+	return req, nil
+}
+
+// setValue parses the given text into v, which must be settable. Strings, bools, numbers,
+// durations, and text unmarshalers are parsed from their text and anything else from JSON.
+func setValue(v reflect.Value, text string) error {
+	if v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text))
+	}
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(text)
+		v.SetInt(int64(d))
+		return err
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(text)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(text, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(text, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(text, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Pointer:
+		elem := reflect.New(v.Type().Elem())
+		if err := setValue(elem.Elem(), text); err != nil {
+			return err
+		}
+		v.Set(elem)
+	default:
+		return json.Unmarshal([]byte(text), v.Addr().Interface())
+	}
+	return nil
+}
+
+// describe is the usage of the flag for a field of the given type.
+func describe(type_ reflect.Type) string {
+	for type_.Kind() == reflect.Pointer {
+		type_ = type_.Elem()
+	}
+	if reflect.PointerTo(type_).Implements(textUnmarshalerType) || type_ == durationType {
+		return type_.String()
+	}
+	switch type_.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return type_.Kind().String()
+	}
+	return "JSON " + type_.String()
+}
@@ -0,0 +1,266 @@
+// Package stahpcli runs [stahp.Target] functions from the terminal so that the operations an
+// application serves over HTTP can also be run by hand, e.g. by ops from a shell on the host.
+//
+// Every Target registered with an [App] becomes a subcommand. The request is built from flags
+// named after the `json` tags of the fields of the request struct, the same names the request is
+// decoded with over HTTP, or decoded from JSON on stdin with -stdin. The response is printed as
+// JSON, or as a table with -output=table, and errors are mapped to the exit code of the process.
+//
+//	app := stahpcli.New("users")
+//	stahpcli.Register(app, "add", "adds a user", a.addUser)
+//	stahpcli.Register(app, "list", "lists the users", a.getUsers)
+//	app.Main()
+//
+//	$ users add -name alice
+//	$ echo '{"name":"bob"}' | users add -stdin
+//	$ users list -output=table
+package stahpcli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/ttd2089/stahp"
+)
+
+// The exit codes returned by [App.Run].
+const (
+	// ExitOK is returned when the target succeeds.
+	ExitOK = 0
+	// ExitFailure is returned when the target returns an error, unless [App.ExitCode] maps it to
+	// another exit code.
+	ExitFailure = 1
+	// ExitUsage is returned when the command line can't be parsed into a request, e.g. because the
+	// subcommand doesn't exist or a flag has an invalid value.
+	ExitUsage = 2
+	// ExitInterrupted is returned when the target returns [context.Canceled], i.e. when it's
+	// interrupted by a signal while running under [App.Main].
+	ExitInterrupted = 130
+)
+
+// ErrUsage is returned by the request parsing of a subcommand when the command line is invalid.
+// Errors wrapping ErrUsage are mapped to [ExitUsage].
+var ErrUsage = errors.New("invalid usage")
+
+// ErrCommandExists is returned by [Register] when a subcommand with the same name has already been
+// registered.
+var ErrCommandExists = errors.New("command already registered")
+
+// An App is a command line application whose subcommands run [stahp.Target] functions.
+type App struct {
+	// Name is the name of the application used in usage and error messages.
+	Name string
+	// Stdin is where requests are read from with -stdin. The default is [os.Stdin].
+	Stdin io.Reader
+	// Stdout is where responses are written. The default is [os.Stdout].
+	Stdout io.Writer
+	// Stderr is where usage and errors are written. The default is [os.Stderr].
+	Stderr io.Writer
+	// ExitCode maps the errors returned by targets to exit codes. The default is
+	// [DefaultExitCode], which custom mappings can fall back to.
+	ExitCode func(error) int
+
+	commands []*command
+}
+
+// New creates an [App] with the given name.
+func New(name string) *App {
+	return &App{Name: name}
+}
+
+// A command is a subcommand of an [App] that runs a target.
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, app *App, args []string) int
+}
+
+// Register adds a subcommand to the given [App] which runs the given [stahp.Target]. The usage is
+// a short description of the subcommand shown in the usage of the App.
+//
+// A struct Req is built from flags named after the `json` tags of its exported fields, or from its
+// field names for fields without tags like [encoding/json]. Strings, bools, numbers,
+// [time.Duration], and types implementing [encoding.TextUnmarshaler] are parsed from their text
+// and anything else, e.g. slices, from JSON. Any other Req is parsed from a single argument.
+// Either way the request can instead be decoded from JSON on stdin with -stdin, in which case
+// the flags override the fields they're given for.
+func Register[Req any, Resp any](app *App, name string, usage string, target stahp.Target[Req, Resp]) error {
+	if app == nil {
+		return errors.New("cannot register commands to a nil App")
+	}
+	if name == "" || strings.ContainsAny(name, " \t\n") || strings.HasPrefix(name, "-") {
+		return fmt.Errorf("invalid command name %q", name)
+	}
+	if target == nil {
+		return fmt.Errorf("cannot register nil target for command %q", name)
+	}
+	if slices.ContainsFunc(app.commands, func(existing *command) bool { return existing.name == name }) {
+		return fmt.Errorf("%w: %s", ErrCommandExists, name)
+	}
+	// Find fields that conflict with the flags of every command now rather than when it's run.
+	if _, err := newCommandFlags[Req](app, name); err != nil {
+		return err
+	}
+	app.commands = append(app.commands, &command{
+		name:  name,
+		usage: usage,
+		run: func(ctx context.Context, app *App, args []string) int {
+			return runTarget(ctx, app, name, args, target)
+		},
+	})
+	return nil
+}
+
+// Run runs the subcommand named by the first of the given arguments with the rest of them, which
+// shouldn't include the name of the program, and returns the exit code of the process.
+func (app *App) Run(ctx context.Context, args []string) int {
+	if len(args) == 0 {
+		app.usage()
+		return ExitUsage
+	}
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		app.usage()
+		return ExitOK
+	}
+	for _, command := range app.commands {
+		if command.name == args[0] {
+			return command.run(ctx, app, args[1:])
+		}
+	}
+	fmt.Fprintf(app.stderr(), "%s: unknown command %q\n", app.Name, args[0])
+	app.usage()
+	return ExitUsage
+}
+
+// Main runs the subcommand named by the arguments of the process, cancelling the context of the
+// target on SIGINT or SIGTERM, and exits with its exit code.
+func (app *App) Main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := app.Run(ctx, os.Args[1:])
+	stop()
+	os.Exit(code)
+}
+
+// DefaultExitCode maps the errors returned by targets to exit codes: [ExitOK] for nil,
+// [ExitUsage] for errors wrapping [ErrUsage], [ExitInterrupted] for [context.Canceled], and
+// [ExitFailure] for everything else.
+func DefaultExitCode(err error) int {
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, ErrUsage):
+		return ExitUsage
+	case errors.Is(err, context.Canceled):
+		return ExitInterrupted
+	default:
+		return ExitFailure
+	}
+}
+
+// commandFlags are the flags of a subcommand.
+type commandFlags[Req any] struct {
+	*flag.FlagSet
+	stdin  *bool
+	output *string
+	parser *requestParser[Req]
+}
+
+func newCommandFlags[Req any](app *App, name string) (*commandFlags[Req], error) {
+	flags := &commandFlags[Req]{FlagSet: flag.NewFlagSet(app.Name+" "+name, flag.ContinueOnError)}
+	flags.SetOutput(app.stderr())
+	flags.stdin = flags.Bool("stdin", false, "decode the request from JSON on stdin")
+	flags.output = flags.String("output", formatJSON, "the `format` of the response: json or table")
+	parser, err := newRequestParser[Req](flags.FlagSet)
+	if err != nil {
+		return nil, err
+	}
+	flags.parser = parser
+	return flags, nil
+}
+
+func runTarget[Req any, Resp any](ctx context.Context, app *App, name string, args []string, target stahp.Target[Req, Resp]) int {
+	// Register has already checked the flags.
+	flags, _ := newCommandFlags[Req](app, name)
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitOK
+		}
+		return ExitUsage
+	}
+	if *flags.output != formatJSON && *flags.output != formatTable {
+		fmt.Fprintf(app.stderr(), "%s %s: unknown output format %q\n", app.Name, name, *flags.output)
+		return ExitUsage
+	}
+	var stdin io.Reader
+	if *flags.stdin {
+		stdin = app.stdin()
+	}
+	req, err := flags.parser.parse(stdin, flags.Args())
+	if err != nil {
+		fmt.Fprintf(app.stderr(), "%s %s: %s\n", app.Name, name, err)
+		flags.Usage()
+		return ExitUsage
+	}
+
+	resp, err := target(ctx, req)
+	if err != nil {
+		fmt.Fprintf(app.stderr(), "%s %s: %s\n", app.Name, name, err)
+		exitCode := app.ExitCode
+		if exitCode == nil {
+			exitCode = DefaultExitCode
+		}
+		// A mapping that returns ExitOK for an error would make the failure look like a success.
+		if code := exitCode(err); code != ExitOK {
+			return code
+		}
+		return ExitFailure
+	}
+	if err := writeResponse(app.stdout(), *flags.output, resp); err != nil {
+		fmt.Fprintf(app.stderr(), "%s %s: writing response: %s\n", app.Name, name, err)
+		return ExitFailure
+	}
+	return ExitOK
+}
+
+func (app *App) usage() {
+	w := app.stderr()
+	fmt.Fprintf(w, "Usage: %s <command> [flags]\n\nCommands:\n", app.Name)
+	width := 0
+	for _, command := range app.commands {
+		width = max(width, len(command.name))
+	}
+	for _, command := range app.commands {
+		fmt.Fprintf(w, "  %-*s  %s\n", width, command.name, command.usage)
+	}
+	fmt.Fprintf(w, "\nRun '%s <command> -h' for the flags of a command.\n", app.Name)
+}
+
+func (app *App) stdin() io.Reader {
+	if app.Stdin == nil {
+		return os.Stdin
+	}
+	return app.Stdin
+}
+
+func (app *App) stdout() io.Writer {
+	if app.Stdout == nil {
+		return os.Stdout
+	}
+	return app.Stdout
+}
+
+func (app *App) stderr() io.Writer {
+	if app.Stderr == nil {
+		return os.Stderr
+	}
+	return app.Stderr
+}
@@ -0,0 +1,217 @@
+package stahpcli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ttd2089/stahp"
+)
+
+type createUserReq struct {
+	Name    string        `json:"name"`
+	Admin   bool          `json:"admin,omitempty"`
+	Age     int           `json:"age"`
+	Tags    []string      `json:"tags"`
+	Timeout time.Duration `json:"timeout"`
+	Ignored string        `json:"-"`
+}
+
+type user struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+var errUserNotFound = errors.New("user not found")
+
+func newTestApp(t *testing.T) (*App, *bytes.Buffer, *bytes.Buffer) {
+	t.Helper()
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	app := New("users")
+	app.Stdout = stdout
+	app.Stderr = stderr
+	app.Stdin = strings.NewReader("")
+	return app, stdout, stderr
+}
+
+func TestRun(t *testing.T) {
+
+	var received createUserReq
+	addUser := func(_ context.Context, req createUserReq) (user, error) {
+		received = req
+		return user{ID: 1, Name: req.Name}, nil
+	}
+	getUser := func(_ context.Context, id int) (user, error) {
+		if id != 1 {
+			return user{}, errUserNotFound
+		}
+		return user{ID: 1, Name: "alice"}, nil
+	}
+	listUsers := func(context.Context) ([]user, error) {
+		return []user{{1, "alice"}, {2, "bob"}}, nil
+	}
+
+	newApp := func(t *testing.T) (*App, *bytes.Buffer, *bytes.Buffer) {
+		app, stdout, stderr := newTestApp(t)
+		for _, err := range []error{
+			Register(app, "add", "adds a user", addUser),
+			Register(app, "get", "gets a user", getUser),
+			Register(app, "list", "lists the users", stahp.NoReq(listUsers)),
+		} {
+			if err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+		}
+		return app, stdout, stderr
+	}
+
+	t.Run("builds the request from flags", func(t *testing.T) {
+		app, stdout, stderr := newApp(t)
+		code := app.Run(context.Background(), []string{
+			"add", "-name", "alice", "-admin", "-age=30", "-tags", `["a","b"]`, "-timeout", "5s",
+		})
+		if code != ExitOK {
+			t.Fatalf("expected %d; got %d: %s", ExitOK, code, stderr)
+		}
+		expected := createUserReq{Name: "alice", Admin: true, Age: 30, Tags: []string{"a", "b"}, Timeout: 5 * time.Second}
+		if received.Name != expected.Name || !received.Admin || received.Age != expected.Age ||
+			strings.Join(received.Tags, ",") != "a,b" || received.Timeout != expected.Timeout {
+			t.Fatalf("expected %+v; got %+v", expected, received)
+		}
+		if expected := "{\n  \"id\": 1,\n  \"name\": \"alice\"\n}\n"; stdout.String() != expected {
+			t.Fatalf("expected %q; got %q", expected, stdout)
+		}
+	})
+
+	t.Run("builds the request from stdin with flags overriding it", func(t *testing.T) {
+		app, _, stderr := newApp(t)
+		app.Stdin = strings.NewReader(`{"name":"bob","age":40}`)
+		if code := app.Run(context.Background(), []string{"add", "-stdin", "-age", "41"}); code != ExitOK {
+			t.Fatalf("expected %d; got %d: %s", ExitOK, code, stderr)
+		}
+		if received.Name != "bob" || received.Age != 41 {
+			t.Fatalf("expected bob aged 41; got %+v", received)
+		}
+	})
+
+	t.Run("builds requests that are not structs from an argument", func(t *testing.T) {
+		app, stdout, stderr := newApp(t)
+		if code := app.Run(context.Background(), []string{"get", "-output=table", "1"}); code != ExitOK {
+			t.Fatalf("expected %d; got %d: %s", ExitOK, code, stderr)
+		}
+		if expected := "id    1\nname  alice\n"; stdout.String() != expected {
+			t.Fatalf("expected %q; got %q", expected, stdout)
+		}
+	})
+
+	t.Run("writes slices of structs as tables", func(t *testing.T) {
+		app, stdout, stderr := newApp(t)
+		if code := app.Run(context.Background(), []string{"list", "-output", "table"}); code != ExitOK {
+			t.Fatalf("expected %d; got %d: %s", ExitOK, code, stderr)
+		}
+		if expected := "ID  NAME\n1   alice\n2   bob\n"; stdout.String() != expected {
+			t.Fatalf("expected %q; got %q", expected, stdout)
+		}
+	})
+
+	testCases := []struct {
+		name     string
+		args     []string
+		exitCode func(error) int
+		expected int
+	}{
+		{name: "no arguments", args: nil, expected: ExitUsage},
+		{name: "help", args: []string{"help"}, expected: ExitOK},
+		{name: "command help", args: []string{"add", "-h"}, expected: ExitOK},
+		{name: "unknown command", args: []string{"remove"}, expected: ExitUsage},
+		{name: "unknown flag", args: []string{"add", "-email", "a@example.com"}, expected: ExitUsage},
+		{name: "invalid flag value", args: []string{"add", "-age", "old"}, expected: ExitUsage},
+		{name: "unexpected argument", args: []string{"add", "alice"}, expected: ExitUsage},
+		{name: "missing argument", args: []string{"get"}, expected: ExitUsage},
+		{name: "invalid argument", args: []string{"get", "one"}, expected: ExitUsage},
+		{name: "unknown output", args: []string{"list", "-output", "xml"}, expected: ExitUsage},
+		{name: "target error", args: []string{"get", "2"}, expected: ExitFailure},
+		{
+			name: "mapped target error",
+			args: []string{"get", "2"},
+			exitCode: func(err error) int {
+				if errors.Is(err, errUserNotFound) {
+					return 3
+				}
+				return DefaultExitCode(err)
+			},
+			expected: 3,
+		},
+		{
+			name:     "target error mapped to ExitOK",
+			args:     []string{"get", "2"},
+			exitCode: func(error) int { return ExitOK },
+			expected: ExitFailure,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			app, _, stderr := newApp(t)
+			app.ExitCode = tc.exitCode
+			if code := app.Run(context.Background(), tc.args); code != tc.expected {
+				t.Fatalf("expected %d; got %d: %s", tc.expected, code, stderr)
+			}
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+
+	noop := func(context.Context, struct{}) (struct{}, error) { return struct{}{}, nil }
+
+	t.Run("returns error for duplicate commands", func(t *testing.T) {
+		app, _, _ := newTestApp(t)
+		Register(app, "noop", "", noop)
+		if err := Register(app, "noop", "", noop); !errors.Is(err, ErrCommandExists) {
+			t.Fatalf("expected %q; got %q", ErrCommandExists, err)
+		}
+	})
+
+	t.Run("returns error for fields that conflict with flags", func(t *testing.T) {
+		app, _, _ := newTestApp(t)
+		type conflicting struct {
+			Output string `json:"output"`
+		}
+		err := Register(app, "conflict", "", func(context.Context, conflicting) (struct{}, error) {
+			return struct{}{}, nil
+		})
+		if err == nil {
+			t.Fatal("expected error; got <nil>")
+		}
+	})
+
+	t.Run("returns error for invalid names", func(t *testing.T) {
+		app, _, _ := newTestApp(t)
+		for _, name := range []string{"", "-noop", "no op"} {
+			if err := Register(app, name, "", noop); err == nil {
+				t.Fatalf("expected error for %q; got <nil>", name)
+			}
+		}
+	})
+}
+
+func TestDefaultExitCode(t *testing.T) {
+	testCases := []struct {
+		err      error
+		expected int
+	}{
+		{nil, ExitOK},
+		{errors.New("failed"), ExitFailure},
+		{ErrUsage, ExitUsage},
+		{context.Canceled, ExitInterrupted},
+	}
+	for _, tc := range testCases {
+		if code := DefaultExitCode(tc.err); code != tc.expected {
+			t.Fatalf("expected %d for %v; got %d", tc.expected, tc.err, code)
+		}
+	}
+}